@@ -0,0 +1,127 @@
+package mptt
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const (
+	aggregateNodeAlias    = "mptt_node"
+	aggregateSubtreeAlias = "mptt_subtree"
+)
+
+type aggregateOptions struct {
+	direct bool
+	root   interface{}
+}
+
+// AggregateOption options of AggregateSubtree and AnnotateCumulativeCount
+type AggregateOption func(options *aggregateOptions)
+
+// WithDirectOnly only aggregate the related rows of each node itself, not of its descendants
+func WithDirectOnly() AggregateOption {
+	return func(options *aggregateOptions) {
+		options.direct = true
+	}
+}
+
+// WithAggregateRoot only load root and its descendants, all the trees by default
+func WithAggregateRoot(root interface{}) AggregateOption {
+	return func(options *aggregateOptions) {
+		options.root = root
+	}
+}
+
+// AnnotateCumulativeCount 加载节点到outListPtr，outField为各节点及其子孙节点关联的related行数，
+// related通过fkColumn列关联到节点的id。outField需为只读字段，如`ProductCount int gorm:"->;-:migration"`
+func (t *tree) AnnotateCumulativeCount(outListPtr, related interface{}, fkColumn, outField string, opts ...AggregateOption) error {
+	table, _, err := t.relatedTable(related)
+	if err != nil {
+		return err
+	}
+	expr := "COUNT(" + t.Statement.Quote(table+"."+fkColumn) + ")"
+	return t.AggregateSubtree(outListPtr, related, fkColumn, expr, outField, opts...)
+}
+
+// AggregateSubtree 加载节点到outListPtr，outField为expr对各节点及其子孙节点关联的related行的聚合结果，
+// expr中以表名引用related的列，如"SUM(product.price)"，没有关联的行时为0。
+// 树表与自身按lft BETWEEN lft AND rght连接，再与related连接，一次查询得到所有节点的结果
+func (t *tree) AggregateSubtree(outListPtr, related interface{}, fkColumn, expr, outField string, opts ...AggregateOption) error {
+	if err := t.validateType(outListPtr); err != nil {
+		return err
+	}
+	options := &aggregateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	fields, err := t.lookUpFields(outField)
+	if err != nil {
+		return err
+	}
+	table, relatedDeletedAt, err := t.relatedTable(related)
+	if err != nil {
+		return err
+	}
+	var (
+		quote   = t.Statement.Quote
+		node    = func(column string) string { return quote(aggregateNodeAlias + "." + column) }
+		subtree = func(column string) string { return quote(aggregateSubtreeAlias + "." + column) }
+		// 关联到的节点，WithDirectOnly时为节点自身
+		joined = aggregateNodeAlias
+	)
+	// 表使用了别名，gorm按表名加上的软删除条件不可用，改为自行加上
+	tx := t.Unscoped().Table(t.getTableName() + " " + quote(aggregateNodeAlias)).
+		Select(quote(aggregateNodeAlias) + ".*, COALESCE(" + expr + ", 0) AS " + quote(fields[0].DBName))
+	if t.softDeleted() {
+		tx = tx.Where(node(t.deletedAt.DBName) + " IS NULL")
+	}
+	if !options.direct {
+		joined = aggregateSubtreeAlias
+		on := subtree(t.fields.Tree.DBName) + " = " + node(t.fields.Tree.DBName) +
+			" AND " + subtree(t.fields.Left.DBName) + " BETWEEN " + node(t.fields.Left.DBName) + " AND " + node(t.fields.Right.DBName)
+		for _, field := range t.scopes {
+			on += " AND " + subtree(field.DBName) + " = " + node(field.DBName)
+		}
+		if t.softDeleted() {
+			on += " AND " + subtree(t.deletedAt.DBName) + " IS NULL"
+		}
+		tx = tx.Joins("LEFT JOIN " + t.getTableName() + " " + quote(aggregateSubtreeAlias) + " ON " + on)
+	}
+	on := quote(table+"."+fkColumn) + " = " + quote(joined+"."+t.fields.ID.DBName)
+	if relatedDeletedAt != "" {
+		on += " AND " + quote(table+"."+relatedDeletedAt) + " IS NULL"
+	}
+	tx = tx.Joins("LEFT JOIN " + quote(table) + " ON " + on)
+	if options.root != nil {
+		if err = t.validateType(options.root); err != nil {
+			return err
+		}
+		tx = tx.Scopes(t.TableAs(aggregateNodeAlias).DescendantsOf(options.root, true))
+	}
+	for _, field := range t.scopes {
+		tx = tx.Order(node(field.DBName) + " ASC")
+	}
+	return tx.Group(node(t.fields.ID.DBName)).
+		Order(node(t.fields.Tree.DBName) + " ASC").
+		Order(node(t.fields.Left.DBName) + " ASC").
+		Find(outListPtr).Error
+}
+
+// relatedTable related模型的表名，及其gorm.DeletedAt列（没有时为空）
+func (t *tree) relatedTable(related interface{}) (table, deletedAt string, err error) {
+	if related == nil {
+		return "", "", fmt.Errorf("%w: related model is nil", ModelTypeError)
+	}
+	stmt := &gorm.Statement{DB: t.DB}
+	if err = stmt.Parse(related); err != nil {
+		return "", "", err
+	}
+	for _, field := range stmt.Schema.Fields {
+		if field.FieldType == deletedAtType {
+			deletedAt = field.DBName
+			break
+		}
+	}
+	return stmt.Table, deletedAt, nil
+}
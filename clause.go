@@ -14,9 +14,7 @@ func (t *tree) GetJoinClause(tableName string, colName string) clause.Join {
 
 func (t *tree) GetAncestorsClause(rawItem interface{}, includeSelf bool) clause.Where {
 	cls := clause.Where{
-		Exprs: []clause.Expression{
-			clause.Eq{Column: t.colTree(true), Value: t.getTreeID(rawItem)},
-		},
+		Exprs: t.scopeExprs(rawItem, clause.Eq{Column: t.colTree(true), Value: t.getTreeID(rawItem)}),
 	}
 	if includeSelf {
 		cls.Exprs = append(cls.Exprs,
@@ -34,9 +32,7 @@ func (t *tree) GetAncestorsClause(rawItem interface{}, includeSelf bool) clause.
 
 func (t *tree) GetDescendantsClause(rawItem interface{}, includeSelf bool) clause.Where {
 	cls := clause.Where{
-		Exprs: []clause.Expression{
-			clause.Eq{Column: t.colTree(true), Value: t.getTreeID(rawItem)},
-		},
+		Exprs: t.scopeExprs(rawItem, clause.Eq{Column: t.colTree(true), Value: t.getTreeID(rawItem)}),
 	}
 	if includeSelf {
 		cls.Exprs = append(cls.Exprs,
@@ -51,3 +47,11 @@ func (t *tree) GetDescendantsClause(rawItem interface{}, includeSelf bool) claus
 	}
 	return cls
 }
+
+// scopeExprs append the scope conditions of rawItem to exprs
+func (t *tree) scopeExprs(rawItem interface{}, exprs ...clause.Expression) []clause.Expression {
+	for _, field := range t.scopes {
+		exprs = append(exprs, clause.Eq{Column: field.DBName, Value: getFieldValue(t.context(), rawItem, field)})
+	}
+	return exprs
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	mptt "github.com/boycs007/gorm-mptt"
+)
+
+// errViolations check发现了问题，已输出报告
+var errViolations = errors.New("integrity check failed")
+
+func (a *app) check(args []string) error {
+	fs := a.flagSet("check")
+	trees := fs.String("tree", "", "comma separated tree ids, all the trees by default")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	var treeIDs []int
+	if *trees != "" {
+		for _, value := range strings.Split(*trees, ",") {
+			treeID, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("invalid tree id %q", value)
+			}
+			treeIDs = append(treeIDs, treeID)
+		}
+	}
+	report, err := a.manager.Validate(treeIDs...)
+	if err != nil {
+		return err
+	}
+	if report.Valid() {
+		fmt.Fprintf(a.stdout, "%d nodes checked, no violation\n", report.NodeCount)
+		return nil
+	}
+	kinds := make([]string, 0, len(report.Violations))
+	for kind := range report.Violations {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	fmt.Fprintf(a.stdout, "%d nodes checked\n", report.NodeCount)
+	for _, kind := range kinds {
+		fmt.Fprintf(a.stdout, "%s: %v\n", kind, report.Violations[mptt.ViolationKind(kind)])
+	}
+	fmt.Fprintf(a.stdout, "trees: %v\n", report.TreeIDs)
+	return errViolations
+}
+
+func (a *app) rebuild(args []string) error {
+	fs := a.flagSet("rebuild")
+	treeID := fs.Int("tree", 0, "only rebuild this tree")
+	fast := fs.Bool("fast", false, "rebuild in memory and update the changed rows in batches")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *fast {
+		var treeIDs []int
+		if *treeID > 0 {
+			treeIDs = append(treeIDs, *treeID)
+		}
+		changed, err := a.manager.FastRebuild(treeIDs...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "%d rows changed\n", changed)
+		return nil
+	}
+	var err error
+	if *treeID > 0 {
+		err = a.manager.PartialRebuild(*treeID)
+	} else {
+		err = a.manager.Rebuild()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(a.stdout, "rebuilt")
+	return nil
+}
+
+func (a *app) print(args []string) error {
+	fs := a.flagSet("print")
+	id := fs.String("id", "", "print the subtree of this node, all the trees by default")
+	numbers := fs.Bool("numbers", false, "print lft and rght next to each node")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	root, err := a.optionalNode(*id)
+	if err != nil {
+		return err
+	}
+	var opts []mptt.RenderOption
+	if *numbers {
+		opts = append(opts, mptt.WithRenderNumbers())
+	}
+	return a.manager.PrintTree(a.stdout, root, nil, opts...)
+}
+
+func (a *app) export(args []string) error {
+	fs := a.flagSet("export")
+	format := fs.String("format", "json", "json or csv")
+	csvFormat := fs.String("csv-format", "adjacency", "adjacency (id,parent_id,name) or path")
+	id := fs.String("id", "", "export the subtree of this node, all the trees by default")
+	output := fs.String("o", "", "output file, stdout by default")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	root, err := a.optionalNode(*id)
+	if err != nil {
+		return err
+	}
+	w := a.stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	switch *format {
+	case "json":
+		return a.manager.ExportJSON(w, root, mptt.WithJSONIndent("  "))
+	case "csv":
+		opts := []mptt.CSVOption{mptt.WithCSVFormat(mptt.CSVAdjacency)}
+		switch *csvFormat {
+		case "adjacency":
+		case "path":
+			opts = []mptt.CSVOption{mptt.WithCSVFormat(mptt.CSVPath)}
+		default:
+			return fmt.Errorf("unsupported csv format %q", *csvFormat)
+		}
+		return a.manager.ExportCSV(w, root, opts...)
+	default:
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+}
+
+func (a *app) importTree(args []string) error {
+	fs := a.flagSet("import")
+	format := fs.String("format", "json", "json or csv")
+	input := fs.String("i", "", "input file, stdin by default")
+	targetID := fs.String("target", "", "insert relative to this node, as new trees by default")
+	position := fs.String("position", string(mptt.LastChild), "last-child, first-child, left or right")
+	keepIDs := fs.Bool("keep-ids", false, "insert the nodes with the ids of the input")
+	dryRun := fs.Bool("dry-run", false, "only validate the csv and list the nodes to insert")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	target, err := a.optionalNode(*targetID)
+	if err != nil {
+		return err
+	}
+	var r io.Reader = a.stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	switch *format {
+	case "json":
+		if *dryRun {
+			return errors.New("-dry-run is only supported for csv")
+		}
+		var opts []mptt.JSONOption
+		if *keepIDs {
+			opts = append(opts, mptt.WithJSONKeepIDs())
+		}
+		if err = a.manager.ImportJSON(r, target, mptt.PositionEnum(*position), opts...); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.stdout, "imported")
+		return nil
+	case "csv":
+		var opts []mptt.CSVOption
+		if target != nil {
+			opts = append(opts, mptt.WithCSVTarget(target, mptt.PositionEnum(*position)))
+		}
+		if *keepIDs {
+			opts = append(opts, mptt.WithCSVKeepIDs())
+		}
+		if *dryRun {
+			opts = append(opts, mptt.WithCSVDryRun())
+		}
+		report, err := a.manager.ImportCSV(r, opts...)
+		if err != nil {
+			return err
+		}
+		if report.DryRun {
+			for _, path := range report.Inserts {
+				fmt.Fprintln(a.stdout, "insert", path)
+			}
+		}
+		fmt.Fprintf(a.stdout, "%d rows, %d nodes in %d trees", report.Rows, len(report.Inserts), report.Roots)
+		if report.DryRun {
+			fmt.Fprint(a.stdout, " (dry run)")
+		}
+		fmt.Fprintln(a.stdout)
+		return nil
+	default:
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+}
+
+func (a *app) move(args []string) error {
+	fs := a.flagSet("move")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 3 {
+		fmt.Fprintln(a.stderr, "usage: mptt move ID TARGET POSITION")
+		return errUsage
+	}
+	node, err := a.load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var target interface{}
+	if fs.Arg(1) != "root" {
+		if target, err = a.load(fs.Arg(1)); err != nil {
+			return err
+		}
+	}
+	if _, err = a.manager.MoveNode(node, target, mptt.PositionEnum(fs.Arg(2))); err != nil {
+		return err
+	}
+	fmt.Fprintln(a.stdout, "moved")
+	return nil
+}
+
+// optionalNode id为空时返回nil
+func (a *app) optionalNode(id string) (interface{}, error) {
+	if id == "" {
+		return nil, nil
+	}
+	return a.load(id)
+}
@@ -0,0 +1,21 @@
+module github.com/boycs007/gorm-mptt/cmd/mptt
+
+go 1.18
+
+require (
+	github.com/boycs007/gorm-mptt v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.7.0
+	gorm.io/driver/sqlite v1.4.3
+	gorm.io/gorm v1.24.5
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/mattn/go-sqlite3 v1.14.15 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
+
+replace github.com/boycs007/gorm-mptt => ../../
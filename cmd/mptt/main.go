@@ -0,0 +1,163 @@
+// Command mptt inspects and repairs the mptt columns of a tree table.
+//
+// Usage:
+//
+//	mptt -driver sqlite -dsn app.db -table category [column flags] <command> [flags] [args]
+//
+// Commands:
+//
+//	check   [-tree 1,2]                        validate the mptt columns, exit status 1 on violations
+//	rebuild [-tree N] [-fast]                  rebuild all the trees, or the tree N
+//	print   [-id ID] [-numbers]                print the forest, or the subtree of ID
+//	export  [-format json|csv] [-csv-format adjacency|path] [-id ID] [-o file]
+//	import  [-format json|csv] [-i file] [-target ID] [-position P] [-keep-ids] [-dry-run]
+//	move    ID TARGET POSITION                 TARGET "root" makes ID a new root
+//
+// The column flags (-id-column, -parent-column, -tree-column, -left-column, -right-column,
+// -level-column) map the table columns like mptt.KeyColumnFields.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// drivers 支持的数据库，其他数据库在此注册
+var drivers = map[string]func(dsn string) gorm.Dialector{
+	"sqlite": sqlite.Open,
+}
+
+// errUsage 参数错误，已输出用法
+var errUsage = errors.New("usage error")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "mptt:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+type app struct {
+	db      *gorm.DB
+	manager mptt.TreeManager
+	model   reflect.Type
+	columns columns
+	table   string
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var (
+		a       = &app{stdin: stdin, stdout: stdout, stderr: stderr}
+		fs      = flag.NewFlagSet("mptt", flag.ContinueOnError)
+		driver  = fs.String("driver", "sqlite", "database driver: "+strings.Join(driverNames(), ", "))
+		dsn     = fs.String("dsn", "", "data source name, e.g. the sqlite file")
+		verbose = fs.Bool("verbose", false, "log the sql statements")
+	)
+	fs.SetOutput(stderr)
+	fs.StringVar(&a.table, "table", "", "tree table name")
+	fs.StringVar(&a.columns.id, "id-column", "id", "primary key column")
+	fs.StringVar(&a.columns.parent, "parent-column", "parent_id", "parent id column")
+	fs.StringVar(&a.columns.tree, "tree-column", "tree_id", "tree id column")
+	fs.StringVar(&a.columns.left, "left-column", "lft", "left column")
+	fs.StringVar(&a.columns.right, "right-column", "rght", "right column")
+	fs.StringVar(&a.columns.level, "level-column", "lvl", "level column")
+	fs.StringVar(&a.columns.name, "name-column", "name", "name column used by print and export, empty for none")
+	fs.StringVar(&a.columns.idType, "id-type", "int", "type of the id and parent id columns: int or string")
+	fs.BoolVar(&a.columns.nullParent, "null-parent", false, "the parent id of the roots is NULL instead of the zero value")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: mptt [flags] check|rebuild|print|export|import|move [command flags] [args]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *dsn == "" || a.table == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	open, ok := drivers[*driver]
+	if !ok {
+		return fmt.Errorf("unsupported driver %q", *driver)
+	}
+	logLevel := logger.Silent
+	if *verbose {
+		logLevel = logger.Info
+	}
+	db, err := gorm.Open(open(*dsn), &gorm.Config{Logger: logger.Default.LogMode(logLevel)})
+	if err != nil {
+		return err
+	}
+	if a.model, err = a.columns.modelType(); err != nil {
+		return err
+	}
+	// 模型是动态构造的类型，查询都使用指定的表名
+	a.db = db.Table(a.table).Session(&gorm.Session{})
+	if a.manager, err = mptt.NewTreeManager(a.db, a.newNode(), mptt.WithTableName(a.table)); err != nil {
+		return err
+	}
+
+	commands := map[string]func(args []string) error{
+		"check":   a.check,
+		"rebuild": a.rebuild,
+		"print":   a.print,
+		"export":  a.export,
+		"import":  a.importTree,
+		"move":    a.move,
+	}
+	command, ok := commands[fs.Arg(0)]
+	if !ok {
+		fs.Usage()
+		return errUsage
+	}
+	return command(fs.Args()[1:])
+}
+
+func driverNames() []string {
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *app) newNode() interface{} {
+	return reflect.New(a.model).Interface()
+}
+
+// load 按id查询节点
+func (a *app) load(value string) (interface{}, error) {
+	id, err := a.columns.parseID(value)
+	if err != nil {
+		return nil, err
+	}
+	node := a.newNode()
+	err = a.db.Where(a.db.Statement.Quote(a.columns.id)+" = ?", id).First(node).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("node %s not found", value)
+	}
+	return node, err
+}
+
+// flagSet 子命令的参数
+func (a *app) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("mptt "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	return fs
+}
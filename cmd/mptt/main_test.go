@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCommands(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "tree.db")
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Exec(`CREATE TABLE category (id INTEGER PRIMARY KEY AUTOINCREMENT,
+		parent INTEGER NOT NULL DEFAULT 0, tree_id INTEGER, lvl INTEGER, lft INTEGER, rght INTEGER, title TEXT)`).Error)
+	csvFile := filepath.Join(t.TempDir(), "tree.csv")
+	assert.Nil(t, os.WriteFile(csvFile, []byte("path\nElectronics/Phones/Android\nElectronics/Laptops\nBooks\n"), 0o600))
+
+	exec := func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"-dsn", dsn, "-table", "category", "-parent-column", "parent", "-name-column", "title"}, args...)
+		err := run(args, strings.NewReader(""), &stdout, &stderr)
+		return stdout.String(), err
+	}
+
+	out, err := exec("import", "-format", "csv", "-i", csvFile, "-dry-run")
+	assert.Nil(t, err)
+	assert.Contains(t, out, "insert Electronics/Phones/Android\n")
+	assert.Contains(t, out, "3 rows, 5 nodes in 2 trees (dry run)\n")
+	_, err = exec("import", "-format", "csv", "-i", csvFile)
+	assert.Nil(t, err)
+
+	out, err = exec("print", "-numbers")
+	assert.Nil(t, err)
+	assert.Equal(t, "Electronics (1, 8)\n├── Phones (2, 5)\n│   └── Android (3, 4)\n└── Laptops (6, 7)\nBooks (1, 2)\n", out)
+
+	// Books (id 2) under Phones (id 3)
+	_, err = exec("move", "2", "3", "last-child")
+	assert.Nil(t, err)
+	out, err = exec("check")
+	assert.Nil(t, err)
+	assert.Equal(t, "5 nodes checked, no violation\n", out)
+
+	// break and repair the numbering
+	assert.Nil(t, db.Exec("UPDATE category SET lft = 20 WHERE title = 'Laptops'").Error)
+	out, err = exec("check")
+	assert.ErrorIs(t, err, errViolations)
+	assert.Contains(t, out, "trees: [1]\n")
+	out, err = exec("rebuild", "-fast")
+	assert.Nil(t, err)
+	assert.Equal(t, "1 rows changed\n", out)
+	_, err = exec("check", "-tree", "1")
+	assert.Nil(t, err)
+
+	out, err = exec("export", "-format", "csv", "-csv-format", "path", "-id", "3")
+	assert.Nil(t, err)
+	assert.Equal(t, "path\nPhones\nPhones/Android\nPhones/Books\n", out)
+	out, err = exec("export", "-id", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"id\": 2,\n  \"title\": \"Books\"\n}\n", out)
+
+	_, err = exec("move", "2", "9", "left")
+	assert.EqualError(t, err, "node 9 not found")
+	_, err = exec("unknown")
+	assert.ErrorIs(t, err, errUsage)
+}
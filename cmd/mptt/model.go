@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	mptt "github.com/boycs007/gorm-mptt"
+)
+
+// columns 树表的列名，与mptt.KeyColumnFields对应
+type columns struct {
+	id     string
+	parent string
+	tree   string
+	left   string
+	right  string
+	level  string
+	// name 用于打印、导出的名称列，为空时没有该列
+	name string
+	// idType id及parent_id的类型：int或string
+	idType string
+	// nullParent 根节点的parent_id为NULL
+	nullParent bool
+}
+
+// modelType 按列名构造模型的struct类型，字段名为mptt的默认字段名，json的key为列名
+func (c *columns) modelType() (reflect.Type, error) {
+	var idType reflect.Type
+	switch c.idType {
+	case "int":
+		idType = reflect.TypeOf(int64(0))
+	case "string":
+		idType = reflect.TypeOf("")
+	default:
+		return nil, fmt.Errorf("unsupported id type %q, expected int or string", c.idType)
+	}
+	parentType := idType
+	if c.nullParent {
+		parentType = reflect.PtrTo(idType)
+	}
+	field := func(name string, fieldType reflect.Type, column, tag string) reflect.StructField {
+		return reflect.StructField{
+			Name: name,
+			Type: fieldType,
+			Tag:  reflect.StructTag(fmt.Sprintf(`gorm:"column:%s%s" json:"%s"`, column, tag, column)),
+		}
+	}
+	intType := reflect.TypeOf(0)
+	fields := []reflect.StructField{
+		field(mptt.DefaultIDColumn, idType, c.id, ";primaryKey"),
+		field(mptt.DefaultParentIDColumn, parentType, c.parent, ""),
+		field(mptt.DefaultTreeIDColumn, intType, c.tree, ""),
+		field(mptt.DefaultLevelColumn, intType, c.level, ""),
+		field(mptt.DefaultLeftColumn, intType, c.left, ""),
+		field(mptt.DefaultRightColumn, intType, c.right, ""),
+	}
+	if c.name != "" {
+		fields = append(fields, field("Name", reflect.TypeOf(""), c.name, ""))
+	}
+	return reflect.StructOf(fields), nil
+}
+
+// parseID 命令行参数中的id
+func (c *columns) parseID(value string) (interface{}, error) {
+	if c.idType == "string" {
+		return value, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id %q: %w", value, err)
+	}
+	return id, nil
+}
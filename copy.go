@@ -0,0 +1,241 @@
+package mptt
+
+import (
+	"context"
+	"math"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultBatchSize the number of rows inserted by one statement in CopySubtree and ImportTree
+const DefaultBatchSize = 500
+
+type copyOptions struct {
+	cloneFunc func(source, clone interface{}) error
+	batchSize int
+}
+
+// CopyOption options of CopySubtree
+type CopyOption func(options *copyOptions)
+
+// WithCloneFunc adjust the non-tree columns of each clone before it is inserted, e.g. the name.
+// The mptt columns of clone are already computed, the primary key and the gorm auto create/update time
+// fields are the zero value:
+// set it here when the ids are not generated by the database (e.g. uuid).
+func WithCloneFunc(fn func(source, clone interface{}) error) CopyOption {
+	return func(options *copyOptions) {
+		options.cloneFunc = fn
+	}
+}
+
+// WithCopyBatchSize the number of rows inserted by one statement, default is DefaultBatchSize
+func WithCopyBatchSize(size int) CopyOption {
+	return func(options *copyOptions) {
+		options.batchSize = size
+	}
+}
+
+// CopySubtree 复制source及其子孙节点到target的position位置，target为nil时作为新的树，返回复制出的根节点。
+// 被软删除的节点及其子孙节点不会被复制
+func (t *tree) CopySubtree(source, target interface{}, position PositionEnum, opts ...CopyOption) (interface{}, error) {
+	if err := t.validateType(source); err != nil {
+		return nil, err
+	}
+	if target != nil {
+		if err := t.validateType(target); err != nil {
+			return nil, err
+		}
+	}
+	options := &copyOptions{batchSize: DefaultBatchSize}
+	for _, opt := range opts {
+		opt(options)
+	}
+	var root interface{}
+	err := t.transaction(func(tx *tree) error {
+		stored, err := tx.getNodeByID(tx.getNodeID(source))
+		if err != nil {
+			return err
+		}
+		nodes, err := tx.findNodes(tx.Model(reflectNew(tx.node)).
+			Where(tx.replacePlaceholder("[tree_id] = ? AND [left] >= ? AND [left] <= ?"),
+				tx.getTreeID(stored), tx.getLeft(stored), tx.getRight(stored)).
+			Scopes(tx.scoped(stored)).
+			Order(tx.colLeft() + " ASC"))
+		if err != nil {
+			return err
+		}
+		if nodes = tx.liveNodes(nodes); len(nodes) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		clones := make([]interface{}, len(nodes))
+		for i, node := range nodes {
+			clones[i] = reflectNew(tx.node)
+			reflect.ValueOf(clones[i]).Elem().Set(reflect.ValueOf(node).Elem())
+			tx.setNodeID(clones[i], reflect.Zero(tx.fields.ID.FieldType).Interface())
+			// 创建、更新时间由gorm重新填写
+			for _, field := range tx.autoTimeFields {
+				setFieldValue(tx.context(), clones[i], field, reflect.Zero(field.FieldType).Interface())
+			}
+		}
+		root = clones[0]
+		if target != nil {
+			if target, err = tx.getNodeByID(tx.getNodeID(target)); err != nil {
+				return err
+			}
+			// 复制到其他scope时使用target的scope
+			for _, field := range tx.scopes {
+				for _, clone := range clones {
+					setFieldValue(tx.context(), clone, field, getFieldValue(tx.context(), target, field))
+				}
+			}
+		}
+		place, err := tx.openSlot(root, target, position, len(nodes), 1)
+		if err != nil {
+			return err
+		}
+		tx.setParentID(root, place.parentID)
+		parents := tx.numberNodes(nodes, clones, place.left, place.level, place.treeID)
+		if options.cloneFunc != nil {
+			for i, clone := range clones {
+				if err = options.cloneFunc(nodes[i], clone); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.withBulkInsertHooks(clones, parents, target, position, func() error {
+			return tx.insertLevels(clones, parents, options.batchSize)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// slot 插入位置，newTrees时每个根节点各自作为新的树，tree_id从treeID开始依次分配
+type slot struct {
+	parentID interface{}
+	treeID   int
+	left     int
+	level    int
+	newTrees bool
+}
+
+// openSlot 在target的position位置为count个节点（其中roots个根节点）腾出空间，target为nil时在scopeNode所在scope中作为新的树
+func (t *tree) openSlot(scopeNode, target interface{}, position PositionEnum, count, roots int) (*slot, error) {
+	if target == nil {
+		treeID, err := t.getNextTreeId(scopeNode)
+		if err != nil {
+			return nil, err
+		}
+		return &slot{parentID: t.rootParentID(), treeID: treeID, left: 1, level: 1, newTrees: true}, nil
+	}
+	var (
+		treeID = t.getTreeID(target)
+		left   = t.getLeft(target)
+		right  = t.getRight(target)
+		level  = t.getLevel(target)
+		result = &slot{treeID: treeID}
+	)
+	if t.isRootNode(target) && (position == Left || position == Right) {
+		spaceTarget := treeID - 1
+		if position == Right {
+			spaceTarget = treeID
+		}
+		result = &slot{parentID: t.rootParentID(), treeID: spaceTarget + 1, left: 1, level: 1, newTrees: true}
+		return result, t.createTreeSpace(target, spaceTarget, roots)
+	}
+	switch position {
+	case LastChild:
+		result.left, result.level, result.parentID = right, level+1, t.getNodeID(target)
+	case FirstChild:
+		result.left, result.level, result.parentID = left+1, level+1, t.getNodeID(target)
+	case Left:
+		result.left, result.level, result.parentID = left, level, t.getParentID(target)
+	case Right:
+		result.left, result.level, result.parentID = right+1, level, t.getParentID(target)
+	default:
+		return nil, UnsupportedPositionError
+	}
+	return result, t.createSpace(target, count*2, result.left-1, treeID)
+}
+
+// numberNodes 按nodes（按lft排序的一棵子树）的层级关系为clones依次分配lft、rght、lvl，
+// 不依赖nodes原有lft、rght的连续性（例如其中有被软删除的节点）。返回每个节点的父节点在nodes中的位置，根节点为-1
+func (t *tree) numberNodes(nodes, clones []interface{}, left, level, treeID int) []int {
+	var (
+		counter = left
+		stack   []int
+		parents = make([]int, len(nodes))
+	)
+	pop := func() {
+		t.setRight(clones[stack[len(stack)-1]], counter)
+		counter++
+		stack = stack[:len(stack)-1]
+	}
+	for i, node := range nodes {
+		for len(stack) > 0 && t.getRight(nodes[stack[len(stack)-1]]) < t.getLeft(node) {
+			pop()
+		}
+		parents[i] = -1
+		if len(stack) > 0 {
+			parents[i] = stack[len(stack)-1]
+		}
+		t.setTreeID(clones[i], treeID)
+		t.setLeft(clones[i], counter)
+		t.setLevel(clones[i], level+len(stack))
+		counter++
+		stack = append(stack, i)
+	}
+	for len(stack) > 0 {
+		pop()
+	}
+	return parents
+}
+
+// insertLevels 按层级批量插入nodes，parents为每个节点的父节点在nodes中的位置（-1时parent_id已设置），
+// 上一层插入后以其主键作为下一层的parent_id
+func (t *tree) insertLevels(nodes []interface{}, parents []int, batchSize int) error {
+	var (
+		levels = make(map[int][]interface{})
+		minLvl = math.MaxInt
+		maxLvl = 0
+	)
+	for _, node := range nodes {
+		lvl := t.getLevel(node)
+		levels[lvl] = append(levels[lvl], node)
+		if lvl < minLvl {
+			minLvl = lvl
+		}
+		if lvl > maxLvl {
+			maxLvl = lvl
+		}
+	}
+	for lvl := minLvl; lvl <= maxLvl; lvl++ {
+		if err := t.createInBatches(levels[lvl], batchSize); err != nil {
+			return err
+		}
+		for i, node := range nodes {
+			if parents[i] >= 0 && t.getLevel(node) == lvl+1 {
+				t.setParentID(node, t.getNodeID(nodes[parents[i]]))
+			}
+		}
+	}
+	return nil
+}
+
+// createInBatches 批量插入节点，不经过Plugin的回调，也不保存关联
+func (t *tree) createInBatches(nodes []interface{}, batchSize int) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+	list := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(nodes[0])), 0, len(nodes))
+	for _, node := range nodes {
+		list = reflect.Append(list, reflect.ValueOf(node))
+	}
+	return t.DB.WithContext(context.WithValue(t.context(), pluginSkipKey{}, true)).
+		Omit(clause.Associations).
+		CreateInBatches(list.Interface(), batchSize).Error
+}
@@ -1,26 +1,56 @@
 package mptt
 
 // CreateNode 插入新节点。当节点的ParentID为0时，将生成新的树的根节点；
-// 当节点的ParentID不为0时，将新节点插入为Parent的最后一个子节点
+// 当节点的ParentID不为0时，将新节点插入为Parent的最后一个子节点。
+// 配置了WithOrderInsertionBy时，新节点将按排序字段插入到兄弟节点（或根节点）之间
 func (t *tree) CreateNode(n interface{}) error {
-	var err error
-	if err = t.validateType(n); err != nil {
+	return t.transaction(func(tx *tree) error {
+		return tx.createNode(n)
+	})
+}
+
+func (t *tree) createNode(n interface{}) error {
+	if err := t.validateType(n); err != nil {
 		return err
 	}
+	return t.withInsertHooks(n, nil, LastChild, func() error {
+		return t.createNodeAt(n)
+	})
+}
+
+func (t *tree) createNodeAt(n interface{}) error {
 	parentID := t.getParentID(n)
 	if isEmpty(parentID) {
+		sibling, err := t.nextOrderedSibling(n, nil)
+		if err != nil {
+			return err
+		}
+		if sibling != nil {
+			return t.insertNodeAt(n, sibling, Left)
+		}
 		// new tree root node
-		t.setTreeID(n, t.getNextTreeId())
+		treeID, err := t.getNextTreeId(n)
+		if err != nil {
+			return err
+		}
+		t.setTreeID(n, treeID)
 		t.setLeft(n, 1)
 		t.setRight(n, 2)
 		t.setLevel(n, 1)
-		return t.Statement.Create(n).Error
+		return t.insertRow(n)
 	}
 	parent, err := t.getNodeByID(parentID)
 	if err != nil {
 		return err
 	}
-	return t.InsertNode(n, parent, LastChild)
+	sibling, err := t.nextOrderedSibling(n, parent)
+	if err != nil {
+		return err
+	}
+	if sibling != nil {
+		return t.insertNodeAt(n, sibling, Left)
+	}
+	return t.insertNodeAt(n, parent, LastChild)
 }
 
 // InsertNode 插入新节点
@@ -34,16 +64,31 @@ func (t *tree) CreateNode(n interface{}) error {
 //	Left: 插入到toPtr的左边(前面)
 //	Right: 插入到toPtr的右边(后面)
 //
-// @param refreshToPtr: 是否需要将toPtr对象的信息进行更新，例如如果插入到toPtr的左侧后，toPtr的lft、rght值将会更新
+// toPtr对象的信息会同步更新，例如如果插入到toPtr的左侧后，toPtr的lft、rght值将会更新
 func (t *tree) InsertNode(n, toPtr interface{}, position PositionEnum) error {
+	return t.transaction(func(tx *tree) error {
+		return tx.insertNode(n, toPtr, position)
+	})
+}
+
+func (t *tree) insertNode(n, toPtr interface{}, position PositionEnum) error {
+	if err := t.validateType(n); err != nil {
+		return err
+	}
+	if err := t.validateType(toPtr); err != nil {
+		return err
+	}
+	return t.withInsertHooks(n, toPtr, position, func() error {
+		return t.insertNodeAt(n, toPtr, position)
+	})
+}
+
+func (t *tree) insertNodeAt(n, toPtr interface{}, position PositionEnum) error {
 	var (
 		err  error
 		edge int
 	)
-	if err = t.validateType(n); err != nil {
-		return err
-	}
-	if err = t.validateType(toPtr); err != nil {
+	if err = t.inheritScope(n, toPtr); err != nil {
 		return err
 	}
 	var (
@@ -71,7 +116,7 @@ func (t *tree) InsertNode(n, toPtr interface{}, position PositionEnum) error {
 		if err = t.createTreeSpace(n, spaceTarget, 1); err != nil {
 			return err
 		}
-		return t.Statement.Create(n).Error
+		return t.insertRow(n)
 	}
 
 	switch position {
@@ -111,9 +156,9 @@ func (t *tree) InsertNode(n, toPtr interface{}, position PositionEnum) error {
 
 	t.setTreeID(n, existTreeID)
 
-	err = t.createSpace(2, spaceTarget, existTreeID)
+	err = t.createSpace(toPtr, 2, spaceTarget, existTreeID)
 	if err != nil {
 		return err
 	}
-	return t.Statement.Create(n).Error
+	return t.insertRow(n)
 }
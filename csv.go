@@ -0,0 +1,441 @@
+package mptt
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// CSVFormat the layout of the rows written by ExportCSV, ImportCSV detects it from the header
+type CSVFormat int
+
+const (
+	// CSVAdjacency rows of id,parent_id,name, the parent_id of a root is empty
+	CSVAdjacency CSVFormat = iota
+	// CSVPath rows of path, the names from the root joined by the separator, e.g. Electronics/Phones/Android
+	CSVPath
+)
+
+const (
+	csvIDColumn     = "id"
+	csvParentColumn = "parent_id"
+	csvPathColumn   = "path"
+)
+
+type csvOptions struct {
+	format    CSVFormat
+	nameField string
+	columns   []string
+	separator string
+	target    interface{}
+	position  PositionEnum
+	keepIDs   bool
+	dryRun    bool
+	batchSize int
+}
+
+// CSVOption options of ExportCSV and ImportCSV
+type CSVOption func(options *csvOptions)
+
+// WithCSVFormat the layout written by ExportCSV, default is CSVAdjacency
+func WithCSVFormat(format CSVFormat) CSVOption {
+	return func(options *csvOptions) {
+		options.format = format
+	}
+}
+
+// WithCSVNameField the model field of the name column and of the path segments, default is "Name"
+func WithCSVNameField(name string) CSVOption {
+	return func(options *csvOptions) {
+		options.nameField = name
+	}
+}
+
+// WithCSVColumns more model fields written and read as columns, the header is their column name
+func WithCSVColumns(names ...string) CSVOption {
+	return func(options *csvOptions) {
+		options.columns = names
+	}
+}
+
+// WithCSVPathSeparator the separator of the path segments, default is "/"
+func WithCSVPathSeparator(separator string) CSVOption {
+	return func(options *csvOptions) {
+		options.separator = separator
+	}
+}
+
+// WithCSVTarget import the roots at the position relative to target instead of new trees
+func WithCSVTarget(target interface{}, position PositionEnum) CSVOption {
+	return func(options *csvOptions) {
+		options.target, options.position = target, position
+	}
+}
+
+// WithCSVKeepIDs import the nodes with the values of the id column instead of assigning new ids
+func WithCSVKeepIDs() CSVOption {
+	return func(options *csvOptions) {
+		options.keepIDs = true
+	}
+}
+
+// WithCSVDryRun only read and validate the csv, the report lists the nodes that would be inserted
+func WithCSVDryRun() CSVOption {
+	return func(options *csvOptions) {
+		options.dryRun = true
+	}
+}
+
+// WithCSVBatchSize the number of rows inserted by one statement, default is DefaultBatchSize
+func WithCSVBatchSize(size int) CSVOption {
+	return func(options *csvOptions) {
+		options.batchSize = size
+	}
+}
+
+func newCSVOptions(opts []CSVOption) *csvOptions {
+	options := &csvOptions{nameField: "Name", separator: "/", batchSize: DefaultBatchSize}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// CSVImportReport the result of ImportCSV, nothing is written with WithCSVDryRun
+type CSVImportReport struct {
+	DryRun bool
+	// Rows the number of data rows read
+	Rows int
+	// Roots the number of imported (sub)trees
+	Roots int
+	// Inserts the paths of the nodes inserted, or to insert with WithCSVDryRun, in preorder
+	Inserts []string
+	// Implied the paths of the missing ancestors added for the path format
+	Implied []string
+}
+
+// csvFields name字段及WithCSVColumns的字段
+func (t *tree) csvFields(options *csvOptions) (*schema.Field, []*schema.Field, error) {
+	fields, err := t.lookUpFields(append([]string{options.nameField}, options.columns...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fields[0], fields[1:], nil
+}
+
+// fieldText 字段值的字符串形式，NULL为空字符串
+func (t *tree) fieldText(node interface{}, field *schema.Field) string {
+	value := nullableValue(getFieldValue(t.context(), node, KeyField{Field: field}))
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// ExportCSV 将root及其子孙节点（root为nil时为所有的树）按先序写为csv，格式见CSVFormat
+func (t *tree) ExportCSV(w io.Writer, root interface{}, opts ...CSVOption) error {
+	options := newCSVOptions(opts)
+	nameField, columns, err := t.csvFields(options)
+	if err != nil {
+		return err
+	}
+	nodes, err := t.exportNodes(root)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	header := []string{csvIDColumn, csvParentColumn, nameField.DBName}
+	if options.format == CSVPath {
+		header = []string{csvPathColumn}
+	}
+	for _, field := range columns {
+		header = append(header, field.DBName)
+	}
+	if err = writer.Write(header); err != nil {
+		return err
+	}
+	var (
+		stack []interface{}
+		names []string
+	)
+	for _, node := range nodes {
+		for len(stack) > 0 && !t.inSubtree(stack[len(stack)-1], node) {
+			stack, names = stack[:len(stack)-1], names[:len(names)-1]
+		}
+		name := t.fieldText(node, nameField)
+		var record []string
+		if options.format == CSVPath {
+			if name == "" || strings.Contains(name, options.separator) {
+				return fmt.Errorf("%w: name %q of node %v can not be a path segment", InvalidCSVError, name, t.getNodeID(node))
+			}
+			record = []string{strings.Join(append(names, name), options.separator)}
+		} else {
+			// 导出的根节点的parent_id为空，以便单独导入
+			parentID := ""
+			if len(stack) > 0 {
+				parentID = t.fieldText(node, t.fields.Parent.Field)
+			}
+			record = []string{t.fieldText(node, t.fields.ID.Field), parentID, name}
+		}
+		for _, field := range columns {
+			record = append(record, t.fieldText(node, field))
+		}
+		if err = writer.Write(record); err != nil {
+			return err
+		}
+		stack, names = append(stack, node), append(names, name)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvForest ImportCSV解析出的节点
+type csvForest struct {
+	roots    []interface{}
+	children map[interface{}][]interface{}
+	// implied path格式中缺少的祖先节点
+	implied map[interface{}]bool
+}
+
+func (f *csvForest) add(parent, node interface{}) {
+	if parent == nil {
+		f.roots = append(f.roots, node)
+	} else {
+		f.children[parent] = append(f.children[parent], node)
+	}
+}
+
+// ImportCSV 读取adjacency（id,parent_id,name）或path格式的csv，检查重复的id、path及parent_id环后，
+// 通过ImportTree插入。parent_id为空或0的行为根节点；path格式中缺少的祖先节点以路径的名称补充
+func (t *tree) ImportCSV(r io.Reader, opts ...CSVOption) (*CSVImportReport, error) {
+	options := newCSVOptions(opts)
+	if options.target != nil {
+		if err := t.validateType(options.target); err != nil {
+			return nil, err
+		}
+	}
+	nameField, columns, err := t.csvFields(options)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", InvalidCSVError, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: missing header", InvalidCSVError)
+	}
+	header := make(map[string]int)
+	for i, column := range records[0] {
+		// 表格软件保存的csv可能以BOM开头
+		header[strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))] = i
+	}
+	rows := records[1:]
+	for i, record := range rows {
+		if len(record) != len(records[0]) {
+			return nil, fmt.Errorf("%w: line %d has %d columns, expected %d", InvalidCSVError, i+2, len(record), len(records[0]))
+		}
+	}
+
+	// 除path外各列对应的字段，id列只在WithCSVKeepIDs时写入
+	assigned := make(map[int]*schema.Field)
+	if index, ok := header[csvIDColumn]; ok && options.keepIDs {
+		assigned[index] = t.fields.ID.Field
+	}
+	for _, field := range append([]*schema.Field{nameField}, columns...) {
+		if index, ok := header[field.DBName]; ok {
+			assigned[index] = field
+		}
+	}
+	newNode := func(record []string) (interface{}, error) {
+		node := reflectNew(t.node)
+		for index, field := range assigned {
+			if record[index] == "" {
+				continue
+			}
+			if err := field.Set(t.context(), reflect.ValueOf(node), strings.TrimSpace(record[index])); err != nil {
+				return nil, fmt.Errorf("%w: column %s: %v", InvalidCSVError, field.DBName, err)
+			}
+		}
+		return node, nil
+	}
+
+	var forest *csvForest
+	if _, ok := header[csvPathColumn]; ok {
+		forest, err = t.csvPathForest(rows, header[csvPathColumn], nameField, newNode, options)
+	} else {
+		forest, err = t.csvAdjacencyForest(rows, header, nameField, newNode, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CSVImportReport{DryRun: options.dryRun, Rows: len(rows), Roots: len(forest.roots)}
+	var walk func(node interface{}, path string)
+	walk = func(node interface{}, path string) {
+		if path != "" {
+			path += options.separator
+		}
+		path += t.fieldText(node, nameField)
+		report.Inserts = append(report.Inserts, path)
+		if forest.implied[node] {
+			report.Implied = append(report.Implied, path)
+		}
+		for _, child := range forest.children[node] {
+			walk(child, path)
+		}
+	}
+	for _, root := range forest.roots {
+		walk(root, "")
+	}
+	if options.dryRun || len(forest.roots) == 0 {
+		return report, nil
+	}
+	importOpts := []ImportOption{
+		WithImportChildren(func(node interface{}) []interface{} {
+			return forest.children[node]
+		}),
+		WithImportBatchSize(options.batchSize),
+	}
+	if options.target != nil {
+		importOpts = append(importOpts, WithImportTarget(options.target, options.position))
+	}
+	return report, t.ImportTree(modelSlice(t.node, forest.roots), importOpts...)
+}
+
+// csvAdjacencyForest 按id、parent_id列组装节点，parent_id需指向csv中的行
+func (t *tree) csvAdjacencyForest(rows [][]string, header map[string]int, nameField *schema.Field,
+	newNode func(record []string) (interface{}, error), options *csvOptions) (*csvForest, error) {
+	idIndex, hasID := header[csvIDColumn]
+	parentIndex, hasParent := header[csvParentColumn]
+	if !hasID || !hasParent {
+		return nil, fmt.Errorf("%w: the header needs a %s column, or %s and %s columns",
+			InvalidCSVError, csvPathColumn, csvIDColumn, csvParentColumn)
+	}
+	if _, ok := header[nameField.DBName]; !ok {
+		return nil, fmt.Errorf("%w: missing column %s", InvalidCSVError, nameField.DBName)
+	}
+	var (
+		forest = &csvForest{children: make(map[interface{}][]interface{})}
+		byID   = make(map[string]interface{}, len(rows))
+		ids    = make([]string, 0, len(rows))
+		nodes  = make([]interface{}, 0, len(rows))
+	)
+	for i, record := range rows {
+		id := strings.TrimSpace(record[idIndex])
+		if id == "" {
+			return nil, fmt.Errorf("%w: line %d: empty id", InvalidCSVError, i+2)
+		}
+		if _, ok := byID[id]; ok {
+			return nil, fmt.Errorf("%w: line %d: duplicate id %s", InvalidCSVError, i+2, id)
+		}
+		node, err := newNode(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+		byID[id] = node
+		ids = append(ids, id)
+		nodes = append(nodes, node)
+	}
+	for i, record := range rows {
+		parentID := strings.TrimSpace(record[parentIndex])
+		if parentID == "" || parentID == "0" {
+			forest.add(nil, nodes[i])
+			continue
+		}
+		parent, ok := byID[parentID]
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: unknown parent_id %s", InvalidCSVError, i+2, parentID)
+		}
+		forest.add(parent, nodes[i])
+	}
+
+	// 从根节点不可达的行在parent_id环中
+	reached := make(map[interface{}]bool, len(nodes))
+	queue := append([]interface{}(nil), forest.roots...)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		reached[node] = true
+		queue = append(queue, forest.children[node]...)
+	}
+	if len(reached) < len(nodes) {
+		var cycle []string
+		for i, node := range nodes {
+			if !reached[node] {
+				cycle = append(cycle, ids[i])
+			}
+		}
+		return nil, fmt.Errorf("%w: parent_id cycle among ids %s", InvalidCSVError, strings.Join(cycle, ","))
+	}
+	return forest, nil
+}
+
+// csvPathForest 按path列组装节点，子节点可以在父节点之前出现
+func (t *tree) csvPathForest(rows [][]string, pathIndex int, nameField *schema.Field,
+	newNode func(record []string) (interface{}, error), options *csvOptions) (*csvForest, error) {
+	var (
+		forest = &csvForest{
+			children: make(map[interface{}][]interface{}),
+			implied:  make(map[interface{}]bool),
+		}
+		byPath = make(map[string]interface{}, len(rows))
+	)
+	var ensure func(segments []string) interface{}
+	ensure = func(segments []string) interface{} {
+		key := strings.Join(segments, options.separator)
+		if node, ok := byPath[key]; ok {
+			return node
+		}
+		node := reflectNew(t.node)
+		_ = nameField.Set(t.context(), reflect.ValueOf(node), segments[len(segments)-1])
+		var parent interface{}
+		if len(segments) > 1 {
+			parent = ensure(segments[:len(segments)-1])
+		}
+		forest.add(parent, node)
+		forest.implied[node] = true
+		byPath[key] = node
+		return node
+	}
+	for i, record := range rows {
+		segments := strings.Split(record[pathIndex], options.separator)
+		for j, segment := range segments {
+			segments[j] = strings.TrimSpace(segment)
+			if segments[j] == "" {
+				return nil, fmt.Errorf("%w: line %d: invalid path %q", InvalidCSVError, i+2, record[pathIndex])
+			}
+		}
+		key := strings.Join(segments, options.separator)
+		existing, ok := byPath[key]
+		if ok && !forest.implied[existing] {
+			return nil, fmt.Errorf("%w: line %d: duplicate path %s", InvalidCSVError, i+2, key)
+		}
+		node, err := newNode(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+		if err = nameField.Set(t.context(), reflect.ValueOf(node), segments[len(segments)-1]); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", InvalidCSVError, i+2, err)
+		}
+		if ok {
+			// 之前作为祖先补充的节点，使用该行的值
+			reflect.ValueOf(existing).Elem().Set(reflect.ValueOf(node).Elem())
+			delete(forest.implied, existing)
+			continue
+		}
+		var parent interface{}
+		if len(segments) > 1 {
+			parent = ensure(segments[:len(segments)-1])
+		}
+		forest.add(parent, node)
+		byPath[key] = node
+	}
+	return forest, nil
+}
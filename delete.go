@@ -5,18 +5,24 @@ import (
 )
 
 func (t *tree) DeleteNodeByID(nodeID interface{}) error {
-	// 查询一下确保数据是准的
-	node := reflectNew(t.node)
-	t.setNodeID(node, nodeID)
-	err := t.Model(node).First(node).Error
-	if err != nil {
-		return err
-	}
-	return t.DeleteNode(node, true)
+	return t.transaction(func(tx *tree) error {
+		// 查询一下确保数据是准的
+		node, err := tx.getNodeByID(nodeID)
+		if err != nil {
+			return err
+		}
+		return tx.deleteNode(node, true)
+	})
 }
 
 // DeleteNode delete current node and all descendants
 func (t *tree) DeleteNode(n interface{}, doNotRefresh ...bool) error {
+	return t.transaction(func(tx *tree) error {
+		return tx.deleteNode(n, doNotRefresh...)
+	})
+}
+
+func (t *tree) deleteNode(n interface{}, doNotRefresh ...bool) error {
 	var (
 		err      error
 		realNode = n
@@ -30,7 +36,13 @@ func (t *tree) DeleteNode(n interface{}, doNotRefresh ...bool) error {
 			return err
 		}
 	}
+	return t.withDeleteHooks(realNode, func() error {
+		return t.removeNode(realNode)
+	})
+}
 
+// removeNode 删除realNode及其子孙节点并关闭空隙，realNode需为数据库中的最新值
+func (t *tree) removeNode(realNode interface{}) error {
 	var (
 		right      = t.getRight(realNode)
 		left       = t.getLeft(realNode)
@@ -41,9 +53,27 @@ func (t *tree) DeleteNode(n interface{}, doNotRefresh ...bool) error {
 	diff := right - left + 1
 	whereSql := t.replacePlaceholder("[tree_id] = ? AND [left] >= ? AND [left] < ?")
 	emptyNode := reflectNew(realNode)
-	err = t.Model(emptyNode).
+	switch t.softDeleteMode {
+	case SoftDeleteKeepSlots:
+		// 被软删除的行保留其位置，不关闭空隙
+		return t.Model(emptyNode).
+			Where(whereSql, treeID, left, right).
+			Scopes(t.scoped(realNode)).
+			Delete(map[string]interface{}{}).Error
+	case SoftDeleteArchive:
+		archiveTreeID, err := t.archiveSubtree(realNode)
+		if err != nil {
+			return err
+		}
+		return t.Model(emptyNode).
+			Where(treeDbName+" = ?", archiveTreeID).
+			Scopes(t.scoped(realNode)).
+			Delete(map[string]interface{}{}).Error
+	}
+	err := t.deleteRows(emptyNode).
 		Where(whereSql,
 			treeID, left, right).
+		Scopes(t.scoped(realNode)).
 		Delete(map[string]interface{}{}).Error
 	if err != nil {
 		return err
@@ -52,7 +82,178 @@ func (t *tree) DeleteNode(n interface{}, doNotRefresh ...bool) error {
 		// delete the whole tree, close the tree id gap.
 		return t.Model(emptyNode).
 			Where(treeDbName+" > ?", treeID).
+			Scopes(t.scoped(realNode)).
 			Update(t.colTree(true), gorm.Expr(treeDbName+" - 1")).Error
 	}
-	return t.closeGap(diff, right, treeID)
+	return t.closeGap(realNode, diff, right, treeID)
+}
+
+// DeleteNodeKeepChildren delete current node only, the children are moved up to its position
+func (t *tree) DeleteNodeKeepChildren(n interface{}) error {
+	if err := t.validateType(n); err != nil {
+		return err
+	}
+	return t.transaction(func(tx *tree) error {
+		realNode, err := tx.getNodeByID(tx.getNodeID(n))
+		if err != nil {
+			return err
+		}
+		return tx.withDeleteHooks(realNode, func() error {
+			err := tx.deleteRows(reflectNew(realNode)).
+				Where(tx.colID()+" = ?", tx.getNodeID(realNode)).
+				Scopes(tx.scoped(realNode)).
+				Delete(map[string]interface{}{}).Error
+			if err != nil {
+				return err
+			}
+			if tx.softDeleteMode == SoftDeleteArchive {
+				// 被删除的节点单独作为归档树
+				archiveTreeID, err := tx.getArchiveTreeId(realNode)
+				if err != nil {
+					return err
+				}
+				err = tx.Model(reflectNew(realNode)).Unscoped().
+					Where(tx.colID()+" = ?", tx.getNodeID(realNode)).
+					Select(tx.colTree(), tx.colLeft(), tx.colRight(), tx.colLevel()).
+					Updates(map[string]interface{}{
+						tx.colTree(true):  archiveTreeID,
+						tx.colLeft(true):  1,
+						tx.colRight(true): 2,
+						tx.colLevel(true): 1,
+					}).Error
+				if err != nil {
+					return err
+				}
+			}
+			keepSlot := tx.softDeleteMode == SoftDeleteKeepSlots
+			if tx.isRootNode(realNode) {
+				return tx.splitChildren(realNode, keepSlot)
+			}
+			return tx.liftChildren(realNode, keepSlot)
+		})
+	})
+}
+
+// liftChildren 已删除的非根节点的子节点挂到其父节点下，子孙节点的lft、rght减1、level减1，其后的节点lft、rght减2。
+// keepSlot时被软删除的节点作为叶子节点保留在其子节点之后，其后的节点不变
+func (t *tree) liftChildren(realNode interface{}, keepSlot bool) error {
+	var (
+		left   = t.getLeft(realNode)
+		right  = t.getRight(realNode)
+		values = []interface{}{
+			t.getNodeID(realNode), // children parent_id param
+			left,
+			right,
+			t.getParentID(realNode),
+
+			left, // descendants level param
+			right,
+		}
+		updateSQL = `UPDATE [table_tree] SET 
+        [parent_id] = CASE WHEN [parent_id] = ? AND [left] > ? AND [left] < ? THEN ? ELSE [parent_id] END,
+        [level] = CASE WHEN [left] > ? AND [left] < ? THEN [level] - 1 ELSE [level] END,`
+	)
+	if keepSlot {
+		updateSQL += `
+        [left] = CASE WHEN [left] > ? AND [left] < ? THEN [left] - 1 WHEN [id] = ? THEN ? ELSE [left] END,
+        [right] = CASE WHEN [right] > ? AND [right] < ? THEN [right] - 1 ELSE [right] END
+        WHERE [tree_id] = ? AND [left] >= ? AND [left] < ?`
+		values = append(values,
+			left, // descendants left param
+			right,
+			t.getNodeID(realNode), // deleted node left fix
+			right-1,
+
+			left, // descendants right param
+			right,
+
+			t.getTreeID(realNode),
+			left,
+			right,
+		)
+	} else {
+		updateSQL += `
+        [left] = CASE WHEN [left] > ? AND [left] < ? THEN [left] - 1 WHEN [left] > ? THEN [left] - 2 ELSE [left] END,
+        [right] = CASE WHEN [right] > ? AND [right] < ? THEN [right] - 1 WHEN [right] > ? THEN [right] - 2 ELSE [right] END
+        WHERE [tree_id] = ? AND [right] > ?`
+		values = append(values,
+			left, // descendants left param
+			right,
+			right, // following nodes left fix
+
+			left, // descendants right param
+			right,
+			right, // following nodes right fix
+
+			t.getTreeID(realNode),
+			left,
+		)
+	}
+	return t.execInScope(realNode, t.replacePlaceholder(updateSQL), values...)
+}
+
+// splitChildren 已删除的根节点的每个子节点成为新的树，tree_id从根节点的tree_id开始依次分配，其后的树的tree_id相应后移。
+// keepSlot时被软删除的根节点作为单独的树保留在其子节点的树之后
+func (t *tree) splitChildren(realNode interface{}, keepSlot bool) error {
+	treeID := t.getTreeID(realNode)
+	children, err := t.findNodes(t.slotRows(reflectNew(realNode)).
+		Select(t.colID(), t.colLeft(), t.colRight()).
+		Where(t.colTree()+" = ? AND "+t.colParent()+" = ?", treeID, t.getNodeID(realNode)).
+		Scopes(t.scoped(realNode)).
+		Order(t.colLeft() + " ASC"))
+	if err != nil {
+		return err
+	}
+	shift := len(children) - 1
+	if keepSlot {
+		shift++
+	}
+	if shift != 0 {
+		// 没有子节点时关闭tree_id的空隙
+		err = t.slotRows(reflectNew(realNode)).
+			Where(t.colTree()+" > ?", treeID).
+			Scopes(t.scoped(realNode)).
+			Update(t.colTree(true), gorm.Expr(t.colTree()+" + ?", shift)).Error
+		if err != nil {
+			return err
+		}
+	}
+	updateSQL := t.replacePlaceholder(`UPDATE [table_tree] SET 
+        [parent_id] = CASE WHEN [id] = ? THEN ? ELSE [parent_id] END,
+        [level] = [level] - 1,
+        [tree_id] = ?,
+        [left] = [left] - ?,
+        [right] = [right] - ?
+        WHERE [tree_id] = ? AND [left] >= ? AND [left] <= ?`)
+	// 倒序处理，保证仍在原tree_id中的节点未被修改过
+	for i := len(children) - 1; i >= 0; i-- {
+		var (
+			child  = children[i]
+			offset = t.getLeft(child) - 1
+		)
+		err = t.execInScope(realNode, updateSQL,
+			t.getNodeID(child),
+			t.rootParentID(),
+			treeID+i,
+			offset,
+			offset,
+			treeID,
+			t.getLeft(child),
+			t.getRight(child),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if !keepSlot {
+		return nil
+	}
+	return t.Model(reflectNew(realNode)).Unscoped().
+		Where(t.colID()+" = ?", t.getNodeID(realNode)).
+		Select(t.colTree(), t.colLeft(), t.colRight()).
+		Updates(map[string]interface{}{
+			t.colTree(true):  treeID + len(children),
+			t.colLeft(true):  1,
+			t.colRight(true): 2,
+		}).Error
 }
@@ -1,8 +1,33 @@
 package mptt
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	UnsupportedPositionError = errors.New("unsupported position error")
-	ModelTypeError           = errors.New("tree node data should be a pointer")
+	UnsupportedPositionError    = errors.New("unsupported position error")
+	ModelTypeError              = errors.New("tree node data should be a pointer")
+	UnknownFieldError           = errors.New("unknown model field")
+	ScopeMismatchError          = errors.New("nodes belong to different tree scopes")
+	OrderInsertionByNotSetError = errors.New("order insertion fields are not configured")
+	BulkParentUpdateError       = errors.New("parent can only be updated on a model with primary key")
+	SoftDeleteNotEnabledError   = errors.New("the model has no gorm.DeletedAt field or soft delete is disabled")
+	NodeNotDeletedError         = errors.New("the node is not soft-deleted")
+	DeletedParentError          = errors.New("the parent of the node is deleted, restore it first or give a target")
+	InvalidCSVError             = errors.New("invalid tree csv")
+	UnsupportedOnConflictError  = errors.New("only ON CONFLICT DO NOTHING is supported for existing nodes created through the plugin")
 )
+
+// BrokenParentError parent_id links that can not be rebuilt, returned by Rebuild, PartialRebuild
+// and FastRebuild unless WithPromoteOrphans or WithLostAndFound is configured
+type BrokenParentError struct {
+	// Orphans ids of the nodes whose parent row does not exist
+	Orphans []interface{}
+	// Cycles node ids of each parent_id cycle, e.g. [A B] for A→B→A
+	Cycles [][]interface{}
+}
+
+func (e *BrokenParentError) Error() string {
+	return fmt.Sprintf("broken parent links: orphans %v, cycles %v", e.Orphans, e.Cycles)
+}
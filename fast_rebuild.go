@@ -0,0 +1,253 @@
+package mptt
+
+import (
+	"sort"
+	"strings"
+)
+
+// rebuildBatchSize 每条批量UPDATE语句更新的行数
+const rebuildBatchSize = 500
+
+// rebuildRow 节点重建后的编号
+type rebuildRow struct {
+	id                         interface{}
+	treeID, left, right, level int
+}
+
+// values 顺序与bulkUpdateNumbers中的列一致
+func (r rebuildRow) values() [4]int {
+	return [4]int{r.treeID, r.left, r.right, r.level}
+}
+
+// FastRebuild 修正规则与Rebuild、PartialRebuild一致，但只执行一次查询加载节点的id、parent_id、lft等列，
+// 在内存中计算新的编号后批量更新，并跳过编号未变化的行。
+// 不传treeIDs时修正全部数据；否则只修正指定tree_id的树，与PartialRebuild一致，
+// 按parent_id可达但tree_id错误的子孙节点也会被加载并修正到所在的树中，其原来tree_id的树也会被修正。
+// 返回被更新的行数
+func (t *tree) FastRebuild(treeIDs ...int) (int64, error) {
+	var changed int64
+	err := t.transaction(func(tx *tree) error {
+		var err error
+		changed, err = tx.fastRebuild(treeIDs)
+		return err
+	})
+	return changed, err
+}
+
+func (t *tree) fastRebuild(treeIDs []int) (int64, error) {
+	extraTreeIDs, err := t.repairParents(treeIDs)
+	if err != nil {
+		return 0, err
+	}
+	if len(treeIDs) > 0 {
+		treeIDs = append(append([]int(nil), treeIDs...), extraTreeIDs...)
+	}
+	columns := []string{t.colID(), t.colParent(), t.colTree(), t.colLeft(), t.colRight(), t.colLevel()}
+	orders := make([]string, 0, len(t.scopes)+2)
+	for _, field := range t.scopes {
+		columns = append(columns, t.Statement.Quote(field.DBName))
+		orders = append(orders, t.Statement.Quote(field.DBName)+" ASC")
+	}
+	orders = append(orders, t.colTree()+" ASC", t.colID()+" ASC")
+	query := t.slotRows(reflectNew(t.node)).Select(columns).Order(strings.Join(orders, ", "))
+	if len(treeIDs) > 0 {
+		// tree_id错误的子孙节点被修正到treeIDs的树中后，其原来所在的树留下空缺，也一并修正
+		for {
+			stray, err := t.strayDescendants(treeIDs)
+			if err != nil {
+				return 0, err
+			}
+			if len(stray) == 0 {
+				break
+			}
+			for _, node := range stray {
+				if !containsTreeID(treeIDs, t.getTreeID(node)) {
+					treeIDs = append(treeIDs, t.getTreeID(node))
+				}
+			}
+		}
+		query = query.Where(t.colTree()+" IN ?", treeIDs)
+	}
+	nodes, err := t.findNodes(query)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		roots    []interface{}
+		children = make(map[interface{}][]interface{})
+	)
+	for _, node := range nodes {
+		if t.isRootNode(node) {
+			roots = append(roots, node)
+			continue
+		}
+		parentID := idKey(t.getParentID(node))
+		children[parentID] = append(children[parentID], node)
+	}
+	// 以原有lft为序修正Tree
+	for _, list := range children {
+		sort.SliceStable(list, func(i, j int) bool {
+			return t.getLeft(list[i]) < t.getLeft(list[j])
+		})
+	}
+
+	var rows []rebuildRow
+	var number func(node interface{}, left, treeID, level int) int
+	number = func(node interface{}, left, treeID, level int) int {
+		right := left + 1
+		for _, child := range children[idKey(t.getNodeID(node))] {
+			right = number(child, right, treeID, level+1)
+		}
+		if t.getTreeID(node) != treeID || t.getLeft(node) != left ||
+			t.getRight(node) != right || t.getLevel(node) != level {
+			rows = append(rows, rebuildRow{
+				id:     t.getNodeID(node),
+				treeID: treeID,
+				left:   left,
+				right:  right,
+				level:  level,
+			})
+		}
+		return right + 1
+	}
+
+	for start := 0; start < len(roots); {
+		end := start + 1
+		for end < len(roots) && t.sameScope(roots[start], roots[end]) {
+			end++
+		}
+		rootTreeIDs, err := t.assignTreeIDs(roots[start:end], len(treeIDs) == 0)
+		if err != nil {
+			return 0, err
+		}
+		for i, root := range roots[start:end] {
+			number(root, 1, rootTreeIDs[i], 1)
+		}
+		start = end
+	}
+	return int64(len(rows)), t.bulkUpdateNumbers(rows)
+}
+
+// strayDescendants 父节点在treeIDs的树中（直接或经由其他此类节点），但自身tree_id不在treeIDs中的节点
+func (t *tree) strayDescendants(treeIDs []int) ([]interface{}, error) {
+	var (
+		result  []interface{}
+		loaded              = make(map[interface{}]struct{})
+		parents interface{} = t.slotRows(reflectNew(t.node)).Select(t.colID()).Where(t.colTree()+" IN ?", treeIDs)
+	)
+	for {
+		found, err := t.findNodes(t.slotRows(reflectNew(t.node)).Select(t.colID(), t.colTree()).
+			Where(t.colParent()+" IN (?)", parents).
+			Where(t.colTree()+" NOT IN ?", treeIDs))
+		if err != nil {
+			return nil, err
+		}
+		var ids []interface{}
+		for _, node := range found {
+			id := t.getNodeID(node)
+			if _, ok := loaded[idKey(id)]; ok {
+				continue
+			}
+			loaded[idKey(id)] = struct{}{}
+			result = append(result, node)
+			ids = append(ids, id)
+		}
+		if len(ids) == 0 {
+			return result, nil
+		}
+		parents = ids
+	}
+}
+
+// assignTreeIDs 为同一scope中按tree_id、id排序的根节点分配tree_id，规则与Rebuild一致：
+// 每个tree_id的第一个根节点保留原tree_id，其余根节点（以及tree_id为0的根节点）追加为新的树。
+// compact为true时，再将tree_id修正为从1开始连续
+func (t *tree) assignTreeIDs(roots []interface{}, compact bool) ([]int, error) {
+	var (
+		treeIDs  = make([]int, len(roots))
+		primary  []int
+		appended []int
+	)
+	for i, root := range roots {
+		treeID := t.getTreeID(root)
+		if treeID != 0 && (i == 0 || treeID != t.getTreeID(roots[i-1])) {
+			treeIDs[i] = treeID
+			primary = append(primary, i)
+		} else {
+			appended = append(appended, i)
+		}
+	}
+	if compact {
+		for newTreeID, i := range append(primary, appended...) {
+			treeIDs[i] = newTreeID + 1
+		}
+		return treeIDs, nil
+	}
+	if len(appended) == 0 {
+		return treeIDs, nil
+	}
+	if err := t.context().Err(); err != nil {
+		return nil, err
+	}
+	nextTreeID, err := t.getNextTreeId(roots[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range appended {
+		treeIDs[i] = nextTreeID
+		nextTreeID++
+	}
+	return treeIDs, nil
+}
+
+// bulkUpdateNumbers 分批写入新的编号，每批一条UPDATE语句
+func (t *tree) bulkUpdateNumbers(rows []rebuildRow) error {
+	columns := []string{"[tree_id]", "[left]", "[right]", "[level]"}
+	for start := 0; start < len(rows); start += rebuildBatchSize {
+		if err := t.context().Err(); err != nil {
+			return err
+		}
+		end := start + rebuildBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		var (
+			batch = rows[start:end]
+			sql   strings.Builder
+			vars  = make([]interface{}, 0, len(batch)*len(columns)*2+1)
+			ids   = make([]interface{}, 0, len(batch))
+		)
+		sql.WriteString("UPDATE [table_tree] SET ")
+		for i, column := range columns {
+			if i > 0 {
+				sql.WriteString(", ")
+			}
+			sql.WriteString(column + " = CASE [id]")
+			for _, row := range batch {
+				sql.WriteString(" WHEN ? THEN ?")
+				vars = append(vars, row.id, row.values()[i])
+			}
+			// ELSE分支让数据库按列类型推断参数类型
+			sql.WriteString(" ELSE " + column + " END")
+		}
+		sql.WriteString(" WHERE [id] IN ?")
+		for _, row := range batch {
+			ids = append(ids, row.id)
+		}
+		err := t.Exec(t.replacePlaceholder(sql.String()), append(vars, ids)...).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsTreeID(treeIDs []int, treeID int) bool {
+	for _, id := range treeIDs {
+		if id == treeID {
+			return true
+		}
+	}
+	return false
+}
@@ -2,28 +2,29 @@ package mptt
 
 import (
 	"context"
+	"fmt"
+	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 	"reflect"
 )
 
 // KeyField mptt has id parent_id tree_id left right level fields
-// id parent_id tree_id only support positive integer or string
-// left right level only support positive integer
+// id parent_id support integer (including uint64), string and [16]byte (e.g. uuid.UUID),
+// the zero value of parent_id marks a root node
+// tree_id left right level only support positive integer
 type KeyField struct {
 	*schema.Field
 	Attr string
 }
 
 // setFieldValue ignore error
-func setFieldValue(n interface{}, field KeyField, value interface{}) {
-	ctx := context.Background()
+func setFieldValue(ctx context.Context, n interface{}, field KeyField, value interface{}) {
 	_ = field.Set(ctx, reflect.ValueOf(n), value)
 }
 
-func getIntFieldValue(n interface{}, field KeyField) int {
-	ctx := context.Background()
+func getIntFieldValue(ctx context.Context, n interface{}, field KeyField) int {
 	v, _ := field.ValueOf(ctx, reflect.ValueOf(n))
-	switch data := v.(type) {
+	switch data := nullableValue(v).(type) {
 	case int64:
 		return int(data)
 	case int:
@@ -53,12 +54,38 @@ func getIntFieldValue(n interface{}, field KeyField) int {
 	}
 }
 
-func getFieldValue(n interface{}, field KeyField) interface{} {
-	ctx := context.Background()
+func getFieldValue(ctx context.Context, n interface{}, field KeyField) interface{} {
 	v, _ := field.ValueOf(ctx, reflect.ValueOf(n))
 	return v
 }
 
+// nullableValue 指针或sql.Null*（以及uuid.NullUUID等结构相同的）类型的实际值，为NULL时返回nil
+func nullableValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Struct && value.NumField() == 2 {
+		if valid := value.FieldByName("Valid"); valid.IsValid() && valid.Kind() == reflect.Bool {
+			if !valid.Bool() {
+				return nil
+			}
+			for i := 0; i < 2; i++ {
+				if value.Type().Field(i).Name != "Valid" {
+					return value.Field(i).Interface()
+				}
+			}
+		}
+	}
+	return value.Interface()
+}
+
 type KeyFields struct {
 	ID     KeyField
 	Parent KeyField
@@ -67,3 +94,25 @@ type KeyFields struct {
 	Right  KeyField
 	Level  KeyField
 }
+
+// lookUpFields 按字段名或列名查找模型的字段
+func (t *tree) lookUpFields(names ...string) ([]*schema.Field, error) {
+	stmt := &gorm.Statement{DB: t.DB}
+	if err := stmt.ParseWithSpecialTableName(t.node, t.tableName); err != nil {
+		return nil, err
+	}
+	fields := make([]*schema.Field, 0, len(names))
+	for _, name := range names {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return nil, fmt.Errorf("%w: %s", UnknownFieldError, name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// keyFields 由manager维护的parent、tree、left、right、level列
+func (t *tree) keyFields() []KeyField {
+	return []KeyField{t.fields.Parent, t.fields.Tree, t.fields.Left, t.fields.Right, t.fields.Level}
+}
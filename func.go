@@ -1,9 +1,11 @@
 package mptt
 
 import (
+	"reflect"
 	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // MPTT Table consts
@@ -82,51 +84,83 @@ func (t *tree) colLevel(withoutQuote ...bool) string {
 }
 
 func (t *tree) getNodeID(n interface{}) interface{} {
-	return getFieldValue(n, t.fields.ID)
+	return getFieldValue(t.context(), n, t.fields.ID)
 }
 
+// getParentID 指针或sql.Null*类型的parent_id为NULL时返回nil，否则返回与id类型一致的值，
+// 以便与getNodeID的结果比较或作为map的key
 func (t *tree) getParentID(n interface{}) interface{} {
-	return getFieldValue(n, t.fields.Parent)
+	parentID := nullableValue(getFieldValue(t.context(), n, t.fields.Parent))
+	if parentID == nil {
+		return nil
+	}
+	var (
+		value  = reflect.ValueOf(parentID)
+		idType = t.fields.ID.IndirectFieldType
+	)
+	// 整数不能转换为string类型的id
+	if value.Type() != idType && value.Type().ConvertibleTo(idType) &&
+		(idType.Kind() != reflect.String || value.Kind() == reflect.String) {
+		return value.Convert(idType).Interface()
+	}
+	return parentID
+}
+
+// rootParentID 根节点的parent_id，parent_id为指针或sql.Null*类型时为nil，即NULL
+func (t *tree) rootParentID() interface{} {
+	return t.getParentID(reflectNew(t.node))
+}
+
+// parentCond parent_id为parentID的条件，parentID为nil时为IS NULL
+func (t *tree) parentCond(parentID interface{}) clause.Expression {
+	if parentID == nil {
+		return clause.Expr{SQL: t.colParent() + " IS NULL"}
+	}
+	return clause.Expr{SQL: t.colParent() + " = ?", Vars: []interface{}{parentID}}
 }
 
 func (t *tree) getLeft(n interface{}) int {
-	return getIntFieldValue(n, t.fields.Left)
+	return getIntFieldValue(t.context(), n, t.fields.Left)
 }
 
 func (t *tree) getRight(n interface{}) int {
-	return getIntFieldValue(n, t.fields.Right)
+	return getIntFieldValue(t.context(), n, t.fields.Right)
 }
 
 func (t *tree) getLevel(n interface{}) int {
-	return getIntFieldValue(n, t.fields.Level)
+	return getIntFieldValue(t.context(), n, t.fields.Level)
 }
 
 func (t *tree) getTreeID(n interface{}) int {
-	return getIntFieldValue(n, t.fields.Tree)
+	return getIntFieldValue(t.context(), n, t.fields.Tree)
 }
 
 func (t *tree) setNodeID(n interface{}, value interface{}) {
-	setFieldValue(n, t.fields.ID, value)
+	setFieldValue(t.context(), n, t.fields.ID, value)
 }
 
 func (t *tree) setParentID(n interface{}, value interface{}) {
-	setFieldValue(n, t.fields.Parent, value)
+	// 指针类型的parent_id先置为nil，以免写入到其原来指向的值（例如另一个节点的id）
+	if t.fields.Parent.FieldType.Kind() == reflect.Ptr {
+		setFieldValue(t.context(), n, t.fields.Parent, nil)
+	}
+	setFieldValue(t.context(), n, t.fields.Parent, value)
 }
 
 func (t *tree) setLeft(n interface{}, left int) {
-	setFieldValue(n, t.fields.Left, left)
+	setFieldValue(t.context(), n, t.fields.Left, left)
 }
 
 func (t *tree) setRight(n interface{}, right int) {
-	setFieldValue(n, t.fields.Right, right)
+	setFieldValue(t.context(), n, t.fields.Right, right)
 }
 
 func (t *tree) setLevel(n interface{}, level int) {
-	setFieldValue(n, t.fields.Level, level)
+	setFieldValue(t.context(), n, t.fields.Level, level)
 }
 
 func (t *tree) setTreeID(n interface{}, treeID int) {
-	setFieldValue(n, t.fields.Tree, treeID)
+	setFieldValue(t.context(), n, t.fields.Tree, treeID)
 }
 
 func (t *tree) getNodeByID(id interface{}) (interface{}, error) {
@@ -136,19 +170,97 @@ func (t *tree) getNodeByID(id interface{}) (interface{}, error) {
 	return node, err
 }
 
-func (t *tree) getNextTreeId() int {
+// getNextTreeId 获取n所在scope中的下一个tree_id
+func (t *tree) getNextTreeId(n interface{}) (int, error) {
 	var (
 		treeId       int
 		node         = reflectNew(t.node)
 		treeIdDbName = t.colTree()
 	)
-	t.Statement.Select(treeIdDbName).
-		Model(node).Order(treeIdDbName + " DESC").Limit(1).Scan(&treeId)
-	return treeId + 1
+	err := t.slotRows(node).Select(treeIdDbName).
+		Scopes(t.scoped(n)).Order(treeIdDbName + " DESC").Limit(1).Scan(&treeId).Error
+	if err != nil {
+		return 0, err
+	}
+	return treeId + 1, nil
 }
 
 func (t *tree) createTreeSpace(model interface{}, targetTreeId, num int) error {
-	return t.Model(reflectNew(model)).
+	return t.slotRows(reflectNew(model)).
 		Where(t.colTree()+" > ?", targetTreeId).
+		Scopes(t.scoped(model)).
 		Update(t.colTree(true), gorm.Expr(t.colTree()+" + ?", num)).Error
 }
+
+// scopeSQL 生成将语句限制在n所在scope中的条件，以" AND "开头，没有配置scope时为空
+func (t *tree) scopeSQL(n interface{}) (string, []interface{}) {
+	var (
+		sql  strings.Builder
+		vars = make([]interface{}, 0, len(t.scopes))
+	)
+	for _, field := range t.scopes {
+		sql.WriteString(" AND " + t.Statement.Quote(field.DBName) + " = ?")
+		vars = append(vars, getFieldValue(t.context(), n, field))
+	}
+	return sql.String(), vars
+}
+
+// scoped gorm scope that limits the query to the forest of n
+func (t *tree) scoped(n interface{}) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if n == nil {
+			return db
+		}
+		for _, field := range t.scopes {
+			db = db.Where(clause.Eq{
+				Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName},
+				Value:  getFieldValue(t.context(), n, field),
+			})
+		}
+		return db
+	}
+}
+
+// execInScope 执行rawSql，并限制在n所在的scope中
+func (t *tree) execInScope(n interface{}, rawSql string, values ...interface{}) error {
+	scopeSql, scopeVars := t.scopeSQL(n)
+	return t.Exec(rawSql+scopeSql, append(values, scopeVars...)...).Error
+}
+
+// sameScope 判断两个节点是否属于同一个scope
+func (t *tree) sameScope(a, b interface{}) bool {
+	for _, field := range t.scopes {
+		if !reflect.DeepEqual(getFieldValue(t.context(), a, field), getFieldValue(t.context(), b, field)) {
+			return false
+		}
+	}
+	return true
+}
+
+// inheritScope 新节点未设置scope时继承target的scope，已设置时必须与target一致。
+// 无法区分未设置与显式设置为零值，scope字段为零值（如0、""）时视为未设置，总是继承target的值
+func (t *tree) inheritScope(n, target interface{}) error {
+	for _, field := range t.scopes {
+		value := getFieldValue(t.context(), target, field)
+		if current := getFieldValue(t.context(), n, field); isEmpty(current) {
+			setFieldValue(t.context(), n, field, value)
+		} else if !reflect.DeepEqual(current, value) {
+			return ScopeMismatchError
+		}
+	}
+	return nil
+}
+
+// findNodes 执行查询，并将结果以模型指针列表的形式返回
+func (t *tree) findNodes(db *gorm.DB) ([]interface{}, error) {
+	listPtr := reflect.New(reflect.SliceOf(reflect.TypeOf(reflectNew(t.node))))
+	if err := db.Find(listPtr.Interface()).Error; err != nil {
+		return nil, err
+	}
+	list := listPtr.Elem()
+	nodes := make([]interface{}, list.Len())
+	for i := range nodes {
+		nodes[i] = list.Index(i).Interface()
+	}
+	return nodes, nil
+}
@@ -1,6 +1,10 @@
 module github.com/boycs007/gorm-mptt
 
-go 1.16
+go 1.18
 
 require gorm.io/gorm v1.24.5
 
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.4 // indirect
+)
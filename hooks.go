@@ -0,0 +1,261 @@
+package mptt
+
+import (
+	"gorm.io/gorm"
+)
+
+// HookType the tree mutation and the moment a hook runs
+type HookType string
+
+const (
+	BeforeTreeInsertHook HookType = "before_tree_insert"
+	AfterTreeInsertHook  HookType = "after_tree_insert"
+	BeforeTreeMoveHook   HookType = "before_tree_move"
+	AfterTreeMoveHook    HookType = "after_tree_move"
+	BeforeTreeDeleteHook HookType = "before_tree_delete"
+	AfterTreeDeleteHook  HookType = "after_tree_delete"
+)
+
+// TreeEvent passed to the hooks. The before and after hooks of one operation share the event,
+// the fields describing the result are filled before the after hook runs.
+type TreeEvent struct {
+	// Tx the transaction of the operation, a hook returning an error rolls it back
+	Tx *gorm.DB
+	// Node the inserted, moved or deleted node
+	Node interface{}
+	// Target and Position as passed to the operation, Target is nil for CreateNode and for a move to a new root.
+	// For the nodes inserted by a copy or an import, Target of a child is its parent
+	Target   interface{}
+	Position PositionEnum
+	// OldParentID is nil for an insert, NewParentID is nil for a delete
+	OldParentID interface{}
+	NewParentID interface{}
+	// OldTreeID is 0 for an insert, NewTreeID is 0 for a delete and before the operation
+	OldTreeID int
+	NewTreeID int
+	// Left and Right the lft/rght range of the node (and its descendants): before the operation
+	// in the before hooks (0 for an insert), after the operation in the after hooks
+	// (the deleted range for a delete)
+	Left  int
+	Right int
+}
+
+// Hook manager level hook registered with WithHook
+type Hook func(event *TreeEvent) error
+
+// BeforeTreeInsertInterface implemented by models to run code before the node is inserted
+type BeforeTreeInsertInterface interface {
+	BeforeTreeInsert(event *TreeEvent) error
+}
+
+// AfterTreeInsertInterface implemented by models to run code after the node is inserted
+type AfterTreeInsertInterface interface {
+	AfterTreeInsert(event *TreeEvent) error
+}
+
+// BeforeTreeMoveInterface implemented by models to run code before the subtree is moved
+type BeforeTreeMoveInterface interface {
+	BeforeTreeMove(event *TreeEvent) error
+}
+
+// AfterTreeMoveInterface implemented by models to run code after the subtree is moved
+type AfterTreeMoveInterface interface {
+	AfterTreeMove(event *TreeEvent) error
+}
+
+// BeforeTreeDeleteInterface implemented by models to run code before the subtree is deleted
+type BeforeTreeDeleteInterface interface {
+	BeforeTreeDelete(event *TreeEvent) error
+}
+
+// AfterTreeDeleteInterface implemented by models to run code after the subtree is deleted
+type AfterTreeDeleteInterface interface {
+	AfterTreeDelete(event *TreeEvent) error
+}
+
+// WithHook run hook for every tree mutation of hookType, after the hook method of the model.
+// Hooks run inside the transaction of the operation in the order they are registered.
+// The insert hooks also run for each node inserted by CopySubtree, ImportTree, ImportJSON and
+// ImportCSV: the before hooks of all the nodes first, then the after hooks once the rows are written.
+func WithHook(hookType HookType, hook Hook) Option {
+	return func(options *treeOptions) {
+		options.hooks = append(options.hooks, registeredHook{hookType: hookType, hook: hook})
+	}
+}
+
+type registeredHook struct {
+	hookType HookType
+	hook     Hook
+}
+
+// hasAfterMoveHooks 节点实现了AfterTreeMove，或注册了manager级的AfterTreeMoveHook
+func (t *tree) hasAfterMoveHooks(n interface{}) bool {
+	if _, ok := n.(AfterTreeMoveInterface); ok {
+		return true
+	}
+	for _, item := range t.hooks {
+		if item.hookType == AfterTreeMoveHook {
+			return true
+		}
+	}
+	return false
+}
+
+// runHooks 依次执行模型的hook方法及manager级的hook
+func (t *tree) runHooks(hookType HookType, event *TreeEvent) error {
+	var err error
+	switch hookType {
+	case BeforeTreeInsertHook:
+		if node, ok := event.Node.(BeforeTreeInsertInterface); ok {
+			err = node.BeforeTreeInsert(event)
+		}
+	case AfterTreeInsertHook:
+		if node, ok := event.Node.(AfterTreeInsertInterface); ok {
+			err = node.AfterTreeInsert(event)
+		}
+	case BeforeTreeMoveHook:
+		if node, ok := event.Node.(BeforeTreeMoveInterface); ok {
+			err = node.BeforeTreeMove(event)
+		}
+	case AfterTreeMoveHook:
+		if node, ok := event.Node.(AfterTreeMoveInterface); ok {
+			err = node.AfterTreeMove(event)
+		}
+	case BeforeTreeDeleteHook:
+		if node, ok := event.Node.(BeforeTreeDeleteInterface); ok {
+			err = node.BeforeTreeDelete(event)
+		}
+	case AfterTreeDeleteHook:
+		if node, ok := event.Node.(AfterTreeDeleteInterface); ok {
+			err = node.AfterTreeDelete(event)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, item := range t.hooks {
+		if item.hookType != hookType {
+			continue
+		}
+		if err = item.hook(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parentAt 以position插入到target时的父节点id，target为nil时为根节点
+func (t *tree) parentAt(target interface{}, position PositionEnum) interface{} {
+	if target == nil {
+		return t.rootParentID()
+	}
+	if position == LastChild || position == FirstChild {
+		return t.getNodeID(target)
+	}
+	return t.getParentID(target)
+}
+
+// withInsertHooks 执行插入n的insert，前后执行insert hooks
+func (t *tree) withInsertHooks(n, target interface{}, position PositionEnum, insert func() error) error {
+	event := &TreeEvent{
+		Tx:          t.DB,
+		Node:        n,
+		Target:      target,
+		Position:    position,
+		NewParentID: t.getParentID(n),
+	}
+	if target != nil {
+		event.NewParentID = t.parentAt(target, position)
+	}
+	if err := t.runHooks(BeforeTreeInsertHook, event); err != nil {
+		return err
+	}
+	if err := insert(); err != nil {
+		return err
+	}
+	event.NewParentID = t.getParentID(n)
+	event.NewTreeID, event.Left, event.Right = t.getTreeID(n), t.getLeft(n), t.getRight(n)
+	return t.runHooks(AfterTreeInsertHook, event)
+}
+
+// withBulkInsertHooks 批量插入nodes（CopySubtree、ImportTree）时对每个节点执行insert hooks：
+// 先对所有节点执行before hooks，insert后再依次执行after hooks。parents为父节点在nodes中的位置，
+// 子节点的Target为其父节点，根节点（-1）的Target、Position为操作的target、position
+func (t *tree) withBulkInsertHooks(nodes []interface{}, parents []int, target interface{}, position PositionEnum,
+	insert func() error) error {
+	events := make([]*TreeEvent, len(nodes))
+	for i, node := range nodes {
+		event := &TreeEvent{Tx: t.DB, Node: node, Target: target, Position: position}
+		if parents[i] >= 0 {
+			event.Target, event.Position = nodes[parents[i]], LastChild
+		}
+		event.NewParentID = t.parentAt(event.Target, event.Position)
+		if err := t.runHooks(BeforeTreeInsertHook, event); err != nil {
+			return err
+		}
+		events[i] = event
+	}
+	if err := insert(); err != nil {
+		return err
+	}
+	for i, node := range nodes {
+		event := events[i]
+		event.NewParentID = t.getParentID(node)
+		event.NewTreeID, event.Left, event.Right = t.getTreeID(node), t.getLeft(node), t.getRight(node)
+		if err := t.runHooks(AfterTreeInsertHook, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withMoveHooks 执行移动n的move，前后执行move hooks
+func (t *tree) withMoveHooks(n, target interface{}, position PositionEnum, move func() error) error {
+	event := &TreeEvent{
+		Tx:          t.DB,
+		Node:        n,
+		Target:      target,
+		Position:    position,
+		OldParentID: t.getParentID(n),
+		NewParentID: t.parentAt(target, position),
+		OldTreeID:   t.getTreeID(n),
+		Left:        t.getLeft(n),
+		Right:       t.getRight(n),
+	}
+	if err := t.runHooks(BeforeTreeMoveHook, event); err != nil {
+		return err
+	}
+	if err := move(); err != nil {
+		return err
+	}
+	if !t.hasAfterMoveHooks(n) {
+		return nil
+	}
+	// 移动后n在内存中的值不一定都已更新
+	current, err := t.getNodeByID(t.getNodeID(n))
+	if err != nil {
+		return err
+	}
+	event.NewParentID = t.getParentID(current)
+	event.NewTreeID, event.Left, event.Right = t.getTreeID(current), t.getLeft(current), t.getRight(current)
+	return t.runHooks(AfterTreeMoveHook, event)
+}
+
+// withDeleteHooks 执行删除n（及其子孙节点）的remove，前后执行delete hooks，n需为数据库中的最新值
+func (t *tree) withDeleteHooks(n interface{}, remove func() error) error {
+	event := &TreeEvent{
+		Tx:          t.DB,
+		Node:        n,
+		OldParentID: t.getParentID(n),
+		OldTreeID:   t.getTreeID(n),
+		Left:        t.getLeft(n),
+		Right:       t.getRight(n),
+	}
+	if err := t.runHooks(BeforeTreeDeleteHook, event); err != nil {
+		return err
+	}
+	if err := remove(); err != nil {
+		return err
+	}
+	return t.runHooks(AfterTreeDeleteHook, event)
+}
@@ -0,0 +1,192 @@
+package mptt
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type importOptions struct {
+	childrenField string
+	children      func(node interface{}) []interface{}
+	target        interface{}
+	position      PositionEnum
+	batchSize     int
+}
+
+// ImportOption options of ImportTree
+type ImportOption func(options *importOptions)
+
+// WithImportChildrenField the model field holding the children to import, it should be a slice of
+// model pointers. Default is "Children".
+func WithImportChildrenField(name string) ImportOption {
+	return func(options *importOptions) {
+		options.childrenField = name
+	}
+}
+
+// WithImportChildren read the children of a node with fn instead of the children field
+func WithImportChildren(fn func(node interface{}) []interface{}) ImportOption {
+	return func(options *importOptions) {
+		options.children = fn
+	}
+}
+
+// WithImportParent import the roots as the last children of parent instead of new trees
+func WithImportParent(parent interface{}) ImportOption {
+	return WithImportTarget(parent, LastChild)
+}
+
+// WithImportTarget import the roots at the position relative to target instead of new trees
+func WithImportTarget(target interface{}, position PositionEnum) ImportOption {
+	return func(options *importOptions) {
+		options.target, options.position = target, position
+	}
+}
+
+// WithImportBatchSize the number of rows inserted by one statement, default is DefaultBatchSize
+func WithImportBatchSize(size int) ImportOption {
+	return func(options *importOptions) {
+		options.batchSize = size
+	}
+}
+
+// ImportTree 插入roots（模型指针的slice）及其嵌套的子节点，MPTT信息在内存中计算后按层级批量插入
+func (t *tree) ImportTree(roots interface{}, opts ...ImportOption) error {
+	options := &importOptions{childrenField: DefaultChildrenField, batchSize: DefaultBatchSize}
+	for _, opt := range opts {
+		opt(options)
+	}
+	list := reflect.ValueOf(roots)
+	if list.Kind() != reflect.Slice {
+		return ModelTypeError
+	}
+	if options.children == nil {
+		index, err := t.childrenFieldIndex(options.childrenField)
+		if err != nil {
+			return err
+		}
+		options.children = func(node interface{}) []interface{} {
+			children := reflect.ValueOf(node).Elem().FieldByIndex(index)
+			result := make([]interface{}, children.Len())
+			for i := range result {
+				result[i] = children.Index(i).Interface()
+			}
+			return result
+		}
+	}
+
+	// 按先序展开，parents为父节点在nodes中的位置
+	var (
+		nodes   []interface{}
+		parents []int
+		visited = make(map[interface{}]struct{})
+	)
+	var walk func(node interface{}, parent int) error
+	walk = func(node interface{}, parent int) error {
+		if err := t.validateType(node); err != nil {
+			return err
+		}
+		if _, ok := visited[node]; ok {
+			return fmt.Errorf("%w: node %v is imported twice", ModelTypeError, node)
+		}
+		visited[node] = struct{}{}
+		nodes = append(nodes, node)
+		parents = append(parents, parent)
+		index := len(nodes) - 1
+		for _, child := range options.children(node) {
+			if err := walk(child, index); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i := 0; i < list.Len(); i++ {
+		if err := walk(list.Index(i).Interface(), -1); err != nil {
+			return err
+		}
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	return t.transaction(func(tx *tree) error {
+		var (
+			// place为nil时按各根节点的scope作为新的树
+			place   *slot
+			target  interface{}
+			counter int
+			treeID  int
+			level   = 1
+		)
+		if options.target != nil {
+			var err error
+			if target, err = tx.getNodeByID(tx.getNodeID(options.target)); err != nil {
+				return err
+			}
+			rootCount := 0
+			for i, node := range nodes {
+				if err = tx.inheritScope(node, target); err != nil {
+					return err
+				}
+				if parents[i] < 0 {
+					rootCount++
+				}
+			}
+			if place, err = tx.openSlot(target, target, options.position, len(nodes), rootCount); err != nil {
+				return err
+			}
+			counter, treeID, level = place.left, place.treeID, place.level
+		}
+		// 各scope中下一个tree_id
+		nextTreeIDs := make(map[string]int)
+		var stack []int
+		pop := func() {
+			tx.setRight(nodes[stack[len(stack)-1]], counter)
+			counter++
+			stack = stack[:len(stack)-1]
+		}
+		for i, node := range nodes {
+			for len(stack) > 0 && stack[len(stack)-1] != parents[i] {
+				pop()
+			}
+			if parents[i] >= 0 {
+				if err := tx.inheritScope(node, nodes[parents[i]]); err != nil {
+					return err
+				}
+			} else if place != nil {
+				tx.setParentID(node, place.parentID)
+				if place.newTrees {
+					// 作为根节点的左右兄弟插入时，每个根节点各自一棵树
+					if i > 0 {
+						treeID++
+					}
+					counter = 1
+				}
+			} else {
+				_, scopeVars := tx.scopeSQL(node)
+				key := fmt.Sprint(scopeVars...)
+				if _, ok := nextTreeIDs[key]; !ok {
+					next, err := tx.getNextTreeId(node)
+					if err != nil {
+						return err
+					}
+					nextTreeIDs[key] = next
+				}
+				treeID, counter = nextTreeIDs[key], 1
+				nextTreeIDs[key]++
+				tx.setParentID(node, tx.rootParentID())
+			}
+			tx.setTreeID(node, treeID)
+			tx.setLeft(node, counter)
+			tx.setLevel(node, level+len(stack))
+			counter++
+			stack = append(stack, i)
+		}
+		for len(stack) > 0 {
+			pop()
+		}
+		return tx.withBulkInsertHooks(nodes, parents, target, options.position, func() error {
+			return tx.insertLevels(nodes, parents, options.batchSize)
+		})
+	})
+}
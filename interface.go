@@ -1,6 +1,11 @@
 package mptt
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"io"
+
+	"gorm.io/gorm"
+)
 
 type TreeNode interface {
 	GetAncestors(outListPtr interface{}, ascending, includeSelf bool) error
@@ -12,9 +17,16 @@ type TreeNode interface {
 	GetNextSibling(outPtr interface{}, conds ...interface{}) error
 	GetPreviousSibling(outPtr interface{}, conds ...interface{}) error
 	GetRoot(outPtr interface{}) error
+	// GetTree load the node with its nested descendants into outPtr,
+	// gorm.ErrRecordNotFound when the node is rejected by WithNodeFilter
+	GetTree(outPtr interface{}, opts ...LoadOption) error
+	// GetDescendantCount the number of descendants, computed from lft/rght in memory. With
+	// SoftDeleteKeepSlots it queries the database to skip the soft-deleted descendants; when the
+	// query fails the error is logged and the count of lft/rght (deleted ones included) is returned
 	GetDescendantCount() int
 	GetLevel() int
 	IsChildNode() bool
+	// IsLeafNode no descendant, it queries the database with SoftDeleteKeepSlots like GetDescendantCount
 	IsLeafNode() bool
 	IsRootNode() bool
 	IsDescendantOf(other interface{}, includeSelf bool) bool
@@ -24,15 +36,72 @@ type TreeNode interface {
 // TreeManager ...
 type TreeManager interface {
 	GormDB() *gorm.DB
+	// TreeScopes gorm scopes on the tree table, TableAs returns them for the tree table joined under alias
+	TreeScopes
+	TableAs(alias string) TreeScopes
+	// WithContext returns a TreeManager whose queries all run with ctx,
+	// a cancelled ctx stops long operations such as Rebuild
+	WithContext(ctx context.Context) TreeManager
 	CreateNode(node interface{}) error
+	// SaveNode moves the node when its ParentID differs from the stored row, then saves the other columns
+	SaveNode(node interface{}) error
 	InsertNode(node, target interface{}, position PositionEnum) error
 	MoveNode(node, target interface{}, position PositionEnum, refreshTarget ...bool) (bool, error)
 	MoveNodeByID(nodeID, targetID interface{}, position PositionEnum) (bool, error)
 	DeleteNode(n interface{}, doNotRefresh ...bool) error
 	DeleteNodeByID(nodeID interface{}) error
+	// DeleteNodeKeepChildren deletes only the node, its children take its place under its parent,
+	// the children of a deleted root become roots of new trees
+	DeleteNodeKeepChildren(n interface{}) error
+	// RestoreNode re-inserts a soft-deleted subtree under its old parent (targetPtr is nil)
+	// or at the position relative to targetPtr
+	RestoreNode(n, targetPtr interface{}, position PositionEnum) error
+	ReorderChildren(parent interface{}) error
+	// CopySubtree copies source and its descendants to the position relative to target
+	// (a new tree when target is nil) and returns the copy of source
+	CopySubtree(source, target interface{}, position PositionEnum, opts ...CopyOption) (interface{}, error)
+	// ImportTree inserts roots (a slice of model pointers) and their nested children as new trees,
+	// or at the position given by WithImportParent / WithImportTarget, computing the mptt columns in memory
+	ImportTree(roots interface{}, opts ...ImportOption) error
+	// ExportJSON writes root and its descendants as a nested json object, or all the trees
+	// as an array when root is nil
+	ExportJSON(w io.Writer, root interface{}, opts ...JSONOption) error
+	// ImportJSON reads the json written by ExportJSON and inserts it at the position relative
+	// to target (new trees when target is nil)
+	ImportJSON(r io.Reader, target interface{}, position PositionEnum, opts ...JSONOption) error
+	// ExportCSV writes root and its descendants (all the trees when root is nil) as adjacency
+	// rows id,parent_id,name or as a path column, see CSVFormat
+	ExportCSV(w io.Writer, root interface{}, opts ...CSVOption) error
+	// ImportCSV reads adjacency or path rows, validates them for duplicates and parent_id cycles
+	// and inserts them in bulk, nothing is written with WithCSVDryRun
+	ImportCSV(r io.Reader, opts ...CSVOption) (*CSVImportReport, error)
+	// RenderDOT writes root and its descendants (all the trees when root is nil) as a graphviz digraph
+	RenderDOT(w io.Writer, root interface{}, opts ...RenderOption) error
+	// RenderMermaid writes root and its descendants (all the trees when root is nil) as a mermaid flowchart
+	RenderMermaid(w io.Writer, root interface{}, opts ...RenderOption) error
+	// PrintTree prints node and its descendants (all the trees when node is nil) line by line with
+	// ├──/└── connectors, label is the Name field (or the id) when nil
+	PrintTree(w io.Writer, node interface{}, label func(node interface{}) string, opts ...RenderOption) error
 
 	Rebuild() error
 	PartialRebuild(treeID int) error
+	// FastRebuild rebuild in memory and write back the changed rows in batches,
+	// all trees are rebuilt when no treeIDs is given. Returns the number of changed rows.
+	FastRebuild(treeIDs ...int) (int64, error)
+	// Validate check the mptt columns of the given trees (all trees when no treeID is given)
+	// and report the offending node ids of each violation kind
+	Validate(treeID ...int) (*IntegrityReport, error)
+
+	RootNodes(outListPtr interface{}) error
+	RootNode(treeID int, outPtr interface{}) error
+	// LoadForest load all the trees as nested structures into outListPtr
+	LoadForest(outListPtr interface{}, opts ...LoadOption) error
+	// AnnotateCumulativeCount loads the nodes into outListPtr with outField set to the number of
+	// related rows (linked by fkColumn) of each node and its descendants
+	AnnotateCumulativeCount(outListPtr, related interface{}, fkColumn, outField string, opts ...AggregateOption) error
+	// AggregateSubtree loads the nodes into outListPtr with outField set to expr, e.g. "SUM(product.price)",
+	// over the related rows of each node and its descendants
+	AggregateSubtree(outListPtr, related interface{}, fkColumn, expr, outField string, opts ...AggregateOption) error
 
 	RefreshNode(node interface{}) error
 	Node(node interface{}) TreeNode
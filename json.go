@@ -0,0 +1,268 @@
+package mptt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// DefaultJSONChildrenKey the key holding the nested children in ExportJSON and ImportJSON
+const DefaultJSONChildrenKey = "children"
+
+type jsonOptions struct {
+	fields      []string
+	childrenKey string
+	indent      string
+	keepIDs     bool
+	batchSize   int
+}
+
+// JSONOption options of ExportJSON and ImportJSON
+type JSONOption func(options *jsonOptions)
+
+// WithJSONFields only export the given keys (as named by the json tags of the model) besides the children,
+// e.g. WithJSONFields("id", "name"). Default is all the columns except the mptt ones.
+func WithJSONFields(keys ...string) JSONOption {
+	return func(options *jsonOptions) {
+		options.fields = keys
+	}
+}
+
+// WithJSONChildrenKey the key holding the nested children, default is DefaultJSONChildrenKey
+func WithJSONChildrenKey(key string) JSONOption {
+	return func(options *jsonOptions) {
+		options.childrenKey = key
+	}
+}
+
+// WithJSONIndent indent each level of the exported json with indent
+func WithJSONIndent(indent string) JSONOption {
+	return func(options *jsonOptions) {
+		options.indent = indent
+	}
+}
+
+// WithJSONKeepIDs import the nodes with the ids in the json instead of assigning new ones
+func WithJSONKeepIDs() JSONOption {
+	return func(options *jsonOptions) {
+		options.keepIDs = true
+	}
+}
+
+// WithJSONBatchSize the number of rows inserted by one statement, default is DefaultBatchSize
+func WithJSONBatchSize(size int) JSONOption {
+	return func(options *jsonOptions) {
+		options.batchSize = size
+	}
+}
+
+func newJSONOptions(opts []JSONOption) *jsonOptions {
+	options := &jsonOptions{childrenKey: DefaultJSONChildrenKey, batchSize: DefaultBatchSize}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// ExportJSON 将root及其子孙节点以嵌套的json对象写入w，root为nil时以数组写入所有的树
+func (t *tree) ExportJSON(w io.Writer, root interface{}, opts ...JSONOption) error {
+	options := newJSONOptions(opts)
+	nodes, err := t.exportNodes(root)
+	if err != nil {
+		return err
+	}
+
+	var (
+		roots   []map[string]interface{}
+		stack   []interface{}
+		objects []map[string]interface{}
+		omitted = t.jsonTreeKeys()
+	)
+	for _, node := range nodes {
+		object, err := t.jsonObject(node, options, omitted)
+		if err != nil {
+			return err
+		}
+		for len(stack) > 0 && !t.inSubtree(stack[len(stack)-1], node) {
+			stack, objects = stack[:len(stack)-1], objects[:len(objects)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, object)
+		} else {
+			parent := objects[len(objects)-1]
+			children, _ := parent[options.childrenKey].([]map[string]interface{})
+			parent[options.childrenKey] = append(children, object)
+		}
+		stack, objects = append(stack, node), append(objects, object)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", options.indent)
+	if root != nil {
+		return encoder.Encode(roots[0])
+	}
+	if roots == nil {
+		roots = []map[string]interface{}{}
+	}
+	return encoder.Encode(roots)
+}
+
+// exportNodes root及其子孙节点，root为nil时为所有的节点，按scope、tree_id、lft排序
+func (t *tree) exportNodes(root interface{}) ([]interface{}, error) {
+	tx := t.Model(reflectNew(t.node))
+	if root != nil {
+		if err := t.validateType(root); err != nil {
+			return nil, err
+		}
+		stored, err := t.getNodeByID(t.getNodeID(root))
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where(t.replacePlaceholder("[tree_id] = ? AND [left] >= ? AND [left] <= ?"),
+			t.getTreeID(stored), t.getLeft(stored), t.getRight(stored)).
+			Scopes(t.scoped(stored))
+	} else {
+		for _, field := range t.scopes {
+			tx = tx.Order(t.Statement.Quote(field.DBName) + " ASC")
+		}
+		tx = tx.Order(t.colTree() + " ASC")
+	}
+	return t.findNodes(tx.Order(t.colLeft() + " ASC"))
+}
+
+// jsonObject node按json tag序列化后的对象，不包括omitted中的key，指定了fields时只保留fields
+func (t *tree) jsonObject(node interface{}, options *jsonOptions, omitted map[string]struct{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	object := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	for key := range object {
+		if _, ok := omitted[key]; ok {
+			delete(object, key)
+		}
+	}
+	if len(options.fields) > 0 {
+		selected := make(map[string]interface{}, len(options.fields))
+		for _, key := range options.fields {
+			if value, ok := object[key]; ok {
+				selected[key] = value
+			}
+		}
+		object = selected
+	}
+	delete(object, options.childrenKey)
+	return object, nil
+}
+
+// jsonTreeKeys 导出时省略的key：parent_id、tree_id、lft、rght、lvl、deleted_at及模型的children字段
+func (t *tree) jsonTreeKeys() map[string]struct{} {
+	keys := make(map[string]struct{})
+	for _, field := range []KeyField{t.fields.Parent, t.fields.Tree, t.fields.Left, t.fields.Right, t.fields.Level, t.deletedAt} {
+		if field.Field != nil {
+			keys[jsonKey(field.StructField)] = struct{}{}
+		}
+	}
+	if field, ok := reflect.TypeOf(reflectNew(t.node)).Elem().FieldByName(DefaultChildrenField); ok {
+		keys[jsonKey(field)] = struct{}{}
+	}
+	return keys
+}
+
+// jsonKey 字段在encoding/json中的key
+func jsonKey(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// ImportJSON 读取ExportJSON写入的json（一个对象或对象数组），插入到target的position位置，
+// target为nil时作为新的树。MPTT信息在内存中计算后按层级批量插入
+func (t *tree) ImportJSON(r io.Reader, target interface{}, position PositionEnum, opts ...JSONOption) error {
+	options := newJSONOptions(opts)
+	if target != nil {
+		if err := t.validateType(target); err != nil {
+			return err
+		}
+	}
+	var data interface{}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return err
+	}
+	objects, ok := data.([]interface{})
+	if !ok {
+		objects = []interface{}{data}
+	}
+
+	// 解析后的节点及其子节点
+	children := make(map[interface{}][]interface{})
+	var parse func(value interface{}) (interface{}, error)
+	parse = func(value interface{}) (interface{}, error) {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: json node should be an object, got %T", ModelTypeError, value)
+		}
+		var items []interface{}
+		if value, ok := object[options.childrenKey]; ok && value != nil {
+			if items, ok = value.([]interface{}); !ok {
+				return nil, fmt.Errorf("%w: %s should be an array", ModelTypeError, options.childrenKey)
+			}
+		}
+		fields := make(map[string]interface{}, len(object))
+		for key, value := range object {
+			if key != options.childrenKey {
+				fields[key] = value
+			}
+		}
+		raw, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		node := reflectNew(t.node)
+		if err = json.Unmarshal(raw, node); err != nil {
+			return nil, err
+		}
+		if !options.keepIDs {
+			t.setNodeID(node, reflect.Zero(t.fields.ID.FieldType).Interface())
+		}
+		for _, item := range items {
+			child, err := parse(item)
+			if err != nil {
+				return nil, err
+			}
+			children[node] = append(children[node], child)
+		}
+		return node, nil
+	}
+	roots := make([]interface{}, 0, len(objects))
+	for _, object := range objects {
+		node, err := parse(object)
+		if err != nil {
+			return err
+		}
+		roots = append(roots, node)
+	}
+
+	importOpts := []ImportOption{
+		WithImportChildren(func(node interface{}) []interface{} {
+			return children[node]
+		}),
+		WithImportBatchSize(options.batchSize),
+	}
+	if target != nil {
+		importOpts = append(importOpts, WithImportTarget(target, position))
+	}
+	return t.ImportTree(modelSlice(t.node, roots), importOpts...)
+}
@@ -0,0 +1,159 @@
+package mptt
+
+import (
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// DefaultChildrenField the model field filled by GetTree and LoadForest
+const DefaultChildrenField = "Children"
+
+type loadOptions struct {
+	childrenField string
+	maxDepth      int
+	filter        func(node interface{}) bool
+}
+
+// LoadOption options of GetTree and LoadForest
+type LoadOption func(options *loadOptions)
+
+// WithChildrenField the model field to fill with the children, it should be a slice of
+// model pointers, e.g. `Children []*CustomTree gorm:"-"`. Default is "Children".
+func WithChildrenField(name string) LoadOption {
+	return func(options *loadOptions) {
+		options.childrenField = name
+	}
+}
+
+// WithMaxDepth only load depth levels, the starting node (or roots) is the first level.
+// 0 means no limit.
+func WithMaxDepth(depth int) LoadOption {
+	return func(options *loadOptions) {
+		options.maxDepth = depth
+	}
+}
+
+// WithNodeFilter skip the nodes for which filter returns false, together with their descendants
+func WithNodeFilter(filter func(node interface{}) bool) LoadOption {
+	return func(options *loadOptions) {
+		options.filter = filter
+	}
+}
+
+func newLoadOptions(opts []LoadOption) *loadOptions {
+	options := &loadOptions{childrenField: DefaultChildrenField}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// GetTree load the node and all its descendants with one query, and fill outPtr
+// with the node whose children field holds the nested hierarchy.
+// Returns gorm.ErrRecordNotFound when the node is gone or rejected by WithNodeFilter
+func (t *tree) GetTree(outPtr interface{}, opts ...LoadOption) error {
+	if err := t.validateType(outPtr); err != nil {
+		return err
+	}
+	options := newLoadOptions(opts)
+	whereSql := t.replacePlaceholder("[tree_id] = ? AND [left] >= ? AND [left] <= ?")
+	tx := t.Model(reflectNew(t.node)).
+		Where(whereSql, t.getTreeID(t.node), t.getLeft(t.node), t.getRight(t.node)).
+		Scopes(t.scoped(t.node))
+	if options.maxDepth > 0 {
+		tx = tx.Where(t.colLevel()+" < ?", t.getLevel(t.node)+options.maxDepth)
+	}
+	roots, err := t.loadNested(tx.Order(t.colLeft()+" ASC"), options)
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		// 节点已不存在，或被WithNodeFilter过滤
+		return gorm.ErrRecordNotFound
+	}
+	reflect.ValueOf(outPtr).Elem().Set(reflect.ValueOf(roots[0]).Elem())
+	return nil
+}
+
+// LoadForest load all the trees with one query, and fill outListPtr with the root nodes
+// whose children field holds the nested hierarchy
+func (t *tree) LoadForest(outListPtr interface{}, opts ...LoadOption) error {
+	if err := t.validateType(outListPtr); err != nil {
+		return err
+	}
+	options := newLoadOptions(opts)
+	tx := t.Model(reflectNew(t.node))
+	if options.maxDepth > 0 {
+		tx = tx.Where(t.colLevel()+" <= ?", options.maxDepth)
+	}
+	for _, field := range t.scopes {
+		tx = tx.Order(t.Statement.Quote(field.DBName) + " ASC")
+	}
+	roots, err := t.loadNested(tx.Order(t.colTree()+" ASC").Order(t.colLeft()+" ASC"), options)
+	if err != nil {
+		return err
+	}
+	list := reflect.ValueOf(outListPtr).Elem()
+	list.Set(reflect.MakeSlice(list.Type(), 0, len(roots)))
+	for _, root := range roots {
+		list.Set(reflect.Append(list, reflect.ValueOf(root)))
+	}
+	return nil
+}
+
+// loadNested 查询按scope、tree_id、lft排序的节点，并通过栈遍历组装为嵌套结构，返回各棵（子）树的根
+func (t *tree) loadNested(tx *gorm.DB, options *loadOptions) ([]interface{}, error) {
+	index, err := t.childrenFieldIndex(options.childrenField)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := t.findNodes(tx)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		roots []interface{}
+		stack []interface{}
+		// 被过滤掉的节点，其子孙节点同样跳过
+		skipped interface{}
+	)
+	for _, node := range nodes {
+		if skipped != nil && t.inSubtree(skipped, node) {
+			continue
+		}
+		skipped = nil
+		for len(stack) > 0 && !t.inSubtree(stack[len(stack)-1], node) {
+			stack = stack[:len(stack)-1]
+		}
+		if options.filter != nil && !options.filter(node) {
+			skipped = node
+			continue
+		}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			children := reflect.ValueOf(stack[len(stack)-1]).Elem().FieldByIndex(index)
+			children.Set(reflect.Append(children, reflect.ValueOf(node)))
+		}
+		stack = append(stack, node)
+	}
+	return roots, nil
+}
+
+// inSubtree 判断node是否为ancestor的子孙节点
+func (t *tree) inSubtree(ancestor, node interface{}) bool {
+	return t.getTreeID(ancestor) == t.getTreeID(node) && t.sameScope(ancestor, node) &&
+		t.getLeft(node) > t.getLeft(ancestor) && t.getRight(node) < t.getRight(ancestor)
+}
+
+// childrenFieldIndex children字段的索引，要求为模型指针的slice
+func (t *tree) childrenFieldIndex(name string) ([]int, error) {
+	modelType := reflect.TypeOf(reflectNew(t.node))
+	field, ok := modelType.Elem().FieldByName(name)
+	if !ok || field.Type != reflect.SliceOf(modelType) {
+		return nil, fmt.Errorf("%w: %s should be a slice of %s", UnknownFieldError, name, modelType)
+	}
+	return field.Index, nil
+}
@@ -11,15 +11,18 @@ const (
 )
 
 func (t *tree) MoveNodeByID(nodeID, targetID interface{}, position PositionEnum) (bool, error) {
-	n, err := t.getNodeByID(nodeID)
-	if err != nil {
-		return false, err
-	}
-	target, err := t.getNodeByID(targetID)
-	if err != nil {
-		return false, err
-	}
-	return t.MoveNode(n, target, position)
+	err := t.transaction(func(tx *tree) error {
+		n, err := tx.getNodeByID(nodeID)
+		if err != nil {
+			return err
+		}
+		target, err := tx.getNodeByID(targetID)
+		if err != nil {
+			return err
+		}
+		return tx.moveNode(n, target, position)
+	})
+	return err == nil, err
 }
 
 func (t *tree) MoveNode(n, targetPtr interface{}, position PositionEnum, refreshTarget ...bool) (bool, error) {
@@ -32,6 +35,36 @@ func (t *tree) MoveNode(n, targetPtr interface{}, position PositionEnum, refresh
 			return false, err
 		}
 	}
+	err = t.transaction(func(tx *tree) error {
+		return tx.moveNode(n, targetPtr, position)
+	})
+	if err == nil && len(refreshTarget) > 0 && refreshTarget[0] {
+		err = t.Model(reflectNew(n)).First(targetPtr).Error
+	}
+	return err == nil, err
+}
+
+func (t *tree) moveNode(n, targetPtr interface{}, position PositionEnum) error {
+	if targetPtr != nil && !t.sameScope(n, targetPtr) {
+		return ScopeMismatchError
+	}
+	return t.withMoveHooks(n, targetPtr, position, func() error {
+		return t.moveNodeAt(n, targetPtr, position)
+	})
+}
+
+func (t *tree) moveNodeAt(n, targetPtr interface{}, position PositionEnum) error {
+	var err error
+	if targetPtr == nil || position == LastChild || position == FirstChild {
+		// the new parent is known, keep the configured order among the siblings
+		sibling, err := t.nextOrderedSibling(n, targetPtr)
+		if err != nil {
+			return err
+		}
+		if sibling != nil {
+			targetPtr, position = sibling, Left
+		}
+	}
 	if targetPtr == nil {
 		if t.isChildNode(n) {
 			err = t.makeChildRootNode(n, defaultNewTreeId)
@@ -45,10 +78,7 @@ func (t *tree) MoveNode(n, targetPtr interface{}, position PositionEnum, refresh
 			err = t.moveChildNode(n, targetPtr, position)
 		}
 	}
-	if len(refreshTarget) > 0 && refreshTarget[0] {
-		err = t.Model(reflectNew(n)).First(targetPtr).Error
-	}
-	return err == nil, err
+	return err
 }
 
 // make target node and it's descendants to a new tree
@@ -77,10 +107,12 @@ func (t *tree) makeChildRootNode(n interface{}, treeId int) error {
         WHERE [tree_id] = ?`)
 	)
 	if treeId <= 0 {
-		treeId = t.getNextTreeId()
+		if treeId, err = t.getNextTreeId(n); err != nil {
+			return err
+		}
 	}
 
-	err = t.Statement.Exec(updateSQL,
+	err = t.execInScope(n, updateSQL,
 		lft, // new tree colLvl param
 		rght,
 		lvlOffset,
@@ -104,7 +136,7 @@ func (t *tree) makeChildRootNode(n interface{}, treeId int) error {
 		gapSize,
 
 		rawTreeID, // where condition
-	).Error
+	)
 
 	if err != nil {
 		return err
@@ -112,7 +144,7 @@ func (t *tree) makeChildRootNode(n interface{}, treeId int) error {
 	// fix the values of target model in memory
 	emptyNode := reflectNew(t.node)
 	t.setTreeID(n, treeId)
-	t.setParentID(n, t.getParentID(emptyNode))
+	t.setParentID(n, t.rootParentID())
 	t.setLevel(n, 1)
 	t.setLeft(n, 1)
 	t.setRight(n, rght-offset)
@@ -223,7 +255,7 @@ func (t *tree) moveChildWithinTree(n, targetPtr interface{}, position PositionEn
         [right] = CASE WHEN [right] >= ? AND [right] <= ? THEN [right] + ? WHEN [right] >= ? AND [right] <= ? THEN [right] + ? ELSE [right] END
     	WHERE [tree_id] = ?`)
 
-	err = t.Exec(moveSubtreeSql,
+	err = t.execInScope(n, moveSubtreeSql,
 		lft,
 		rght,
 		lvlOffset,
@@ -245,7 +277,7 @@ func (t *tree) moveChildWithinTree(n, targetPtr interface{}, position PositionEn
 		gapSize,
 
 		treeId,
-	).Error
+	)
 	if err != nil {
 		return err
 	}
@@ -279,7 +311,7 @@ func (t *tree) interTreeMoveAndCloseGap(n interface{}, lvlOffset, offset, newTre
 
 	gapSize := rght - lft + 1
 	gapTargetLeft := lft - 1
-	err = t.Exec(sql,
+	err = t.execInScope(n, sql,
 		lft,
 		rght,
 		lvlOffset,
@@ -297,7 +329,7 @@ func (t *tree) interTreeMoveAndCloseGap(n interface{}, lvlOffset, offset, newTre
 		gapTargetLeft,
 		gapSize,
 		treeId,
-	).Error
+	)
 	return err
 }
 
@@ -373,7 +405,7 @@ func (t *tree) moveRootNode(n, targetPtr interface{}, position PositionEnum) err
 	if err != nil {
 		return err
 	}
-	if err = t.manageSpace(width, spaceTarget, tTreeId); err != nil {
+	if err = t.manageSpace(targetPtr, width, spaceTarget, tTreeId); err != nil {
 		return err
 	}
 
@@ -385,7 +417,7 @@ func (t *tree) moveRootNode(n, targetPtr interface{}, position PositionEnum) err
         WHERE [left] >= ? AND [left] <= ?
         AND [tree_id] = ?`)
 
-	err = t.Exec(moveTreeSql,
+	err = t.execInScope(n, moveTreeSql,
 		lvlOffset,
 		offset,
 		offset,
@@ -393,7 +425,7 @@ func (t *tree) moveRootNode(n, targetPtr interface{}, position PositionEnum) err
 		lft,
 		rght,
 		treeId,
-	).Error
+	)
 	if err != nil {
 		return err
 	}
@@ -456,10 +488,11 @@ func (t *tree) makeSiblingOfRootNode(n, targetPtr interface{}, position Position
 			err = t.Model(emptyNode).Select(
 				t.colID(),
 				t.colTree(),
-			).Where(t.colParent()+" = ? AND "+t.colTree()+" < ?",
-				t.getParentID(emptyNode),
-				tTreeId,
-			).Order(t.colTree() + " desc").First(sibling).Error
+			).Where(t.parentCond(t.rootParentID())).
+				Where(t.colTree()+" < ?", tTreeId).
+				Scopes(t.scoped(n)).
+				Order(t.colTree() + " desc").
+				First(sibling).Error
 			if err != nil {
 				return err
 			}
@@ -486,10 +519,10 @@ func (t *tree) makeSiblingOfRootNode(n, targetPtr interface{}, position Position
 			err = t.Model(emptyNode).Select(
 				t.colID(),
 				t.colTree(),
-			).Where(t.colParent()+" = ? AND "+t.colTree()+" > ?",
-				t.getParentID(emptyNode),
-				tTreeId,
-			).Order(t.colTree() + " asc").
+			).Where(t.parentCond(t.rootParentID())).
+				Where(t.colTree()+" > ?", tTreeId).
+				Scopes(t.scoped(n)).
+				Order(t.colTree() + " asc").
 				First(sibling).Error
 			if err != nil {
 				return err
@@ -509,13 +542,13 @@ func (t *tree) makeSiblingOfRootNode(n, targetPtr interface{}, position Position
 		[tree_id] = CASE WHEN [tree_id] = ? THEN ? ELSE [tree_id] + ? END
     	WHERE [tree_id] >= ? AND [tree_id] <= ?`)
 
-	err = t.Exec(rootSiblingUpdateSql,
+	err = t.execInScope(n, rootSiblingUpdateSql,
 		treeId,
 		newTreeId,
 		shift,
 		lowerBound,
 		upperBound,
-	).Error
+	)
 	if err != nil {
 		return err
 	}
@@ -540,7 +573,7 @@ func (t *tree) moveChildToNewTree(n, targetPtr interface{}, position PositionEnu
 	}
 	treeWidth = rght - lft + 1
 
-	if err = t.manageSpace(treeWidth, spaceTarget, tTreeId); err != nil {
+	if err = t.manageSpace(targetPtr, treeWidth, spaceTarget, tTreeId); err != nil {
 		return err
 	}
 
@@ -567,25 +600,25 @@ func (t *tree) moveChildToNewTree(n, targetPtr interface{}, position PositionEnu
 }
 
 // 用于在删除掉元素后，将树上的左右修正
-func (t *tree) closeGap(size, target, treeId int) error {
-	return t.manageSpace(-size, target, treeId)
+func (t *tree) closeGap(scopeNode interface{}, size, target, treeId int) error {
+	return t.manageSpace(scopeNode, -size, target, treeId)
 }
 
 // 用于在插入了新元素后，将树上的左右修正
-func (t *tree) createSpace(size, target, treeId int) error {
-	return t.manageSpace(size, target, treeId)
+func (t *tree) createSpace(scopeNode interface{}, size, target, treeId int) error {
+	return t.manageSpace(scopeNode, size, target, treeId)
 }
 
-// 根据target来修改特定tree_id的树上的lft rght值
+// 根据target来修改scopeNode所在scope中特定tree_id的树上的lft rght值
 // lft > target的，修改为 lft + size， 否则不修改
 // rght > target的，修改为 rght + size, 否则不修改
-func (t *tree) manageSpace(size, target, treeId int) error {
+func (t *tree) manageSpace(scopeNode interface{}, size, target, treeId int) error {
 	spaceSql := t.replacePlaceholder(`UPDATE [table_tree] SET 
 [left] = CASE WHEN [left] > ? THEN [left] + ? ELSE [left] END,
 [right] = CASE WHEN [right] > ? THEN [right] + ? ELSE [right] END
 WHERE [tree_id] = ? AND ([left] > ? OR [right] > ?)`)
 
-	err := t.Exec(spaceSql,
+	return t.execInScope(scopeNode, spaceSql,
 		target,
 		size,
 		target,
@@ -593,6 +626,5 @@ WHERE [tree_id] = ? AND ([left] > ? OR [right] > ?)`)
 		treeId,
 		target,
 		target,
-	).Error
-	return err
+	)
 }
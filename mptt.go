@@ -1,6 +1,9 @@
 package mptt
 
 import (
+	"context"
+	"fmt"
+
 	"gorm.io/gorm"
 )
 
@@ -9,12 +12,78 @@ type tree struct {
 	node      interface{}
 	tableName string
 	fields    *KeyFields
+	scopes    []KeyField
+	// orderFields 兄弟节点的插入顺序
+	orderFields []KeyField
+	// promoteOrphans、lostAndFoundID 修正前对孤儿节点及parent_id环的处理
+	promoteOrphans bool
+	lostAndFoundID interface{}
+	hooks          []registeredHook
+	// softDeleteMode 模型有gorm.DeletedAt字段deletedAt时删除节点的方式
+	softDeleteMode SoftDeleteMode
+	deletedAt      KeyField
+	// autoTimeFields gorm自动维护的创建、更新时间字段，复制节点时重置
+	autoTimeFields []KeyField
+	// createRow 写入新节点的行，为nil时直接Create，Plugin用它保留原语句的Select/Omit
+	createRow func(db *gorm.DB, n interface{}) error
 }
 
 func (t *tree) GormDB() *gorm.DB {
 	return t.DB
 }
 
+// withDB returns a copy of the tree bound to db, e.g. an opened transaction
+func (t *tree) withDB(db *gorm.DB) *tree {
+	newTree := *t
+	newTree.DB = managerDB(db)
+	return &newTree
+}
+
+// managerDB db安装了Plugin时，管理器执行的语句的ctx都带上pluginSkipKey，不会再次进入插件的回调
+func managerDB(db *gorm.DB) *gorm.DB {
+	if db.Config == nil || db.Config.Plugins[(&Plugin{}).Name()] == nil {
+		return db
+	}
+	ctx := context.Background()
+	if db.Statement != nil && db.Statement.Context != nil {
+		ctx = db.Statement.Context
+	}
+	if skip, _ := ctx.Value(pluginSkipKey{}).(bool); skip {
+		return db
+	}
+	return db.WithContext(context.WithValue(ctx, pluginSkipKey{}, true))
+}
+
+// transaction runs fc in a database transaction. When t.DB is already a
+// transaction, fc joins it through a savepoint, so a failure only rolls back
+// the statements issued by fc.
+func (t *tree) transaction(fc func(tx *tree) error) error {
+	return t.DB.Transaction(func(db *gorm.DB) error {
+		return fc(t.withDB(db))
+	})
+}
+
+// WithContext returns a TreeManager whose queries all run with ctx
+func (t *tree) WithContext(ctx context.Context) TreeManager {
+	return t.withDB(t.DB.WithContext(ctx))
+}
+
+// insertRow 写入已确定mptt列的新节点
+func (t *tree) insertRow(n interface{}) error {
+	if t.createRow != nil {
+		return t.createRow(t.DB, n)
+	}
+	return t.Statement.Create(n).Error
+}
+
+// context the context of current statements
+func (t *tree) context() context.Context {
+	if t.Statement != nil && t.Statement.Context != nil {
+		return t.Statement.Context
+	}
+	return context.Background()
+}
+
 func (t *tree) getTableName() string {
 	return t.Statement.Quote(t.tableName)
 }
@@ -22,6 +91,12 @@ func (t *tree) getTableName() string {
 type treeOptions struct {
 	specialTableName string
 	keyColumns       KeyColumnFields
+	scopeColumns     []string
+	orderInsertionBy []string
+	promoteOrphans   bool
+	lostAndFoundID   interface{}
+	hooks            []registeredHook
+	softDeleteMode   SoftDeleteMode
 }
 
 // ModelBase default mptt base model for user to embedded
@@ -61,10 +136,45 @@ func WithAttrs(fields KeyColumnFields) Option {
 	}
 }
 
+// WithScopeColumns split the table into independent forests by the given model fields,
+// e.g. a tenant id. Tree ids, root ordering and rebuilds are computed per scope value,
+// nodes can not be moved across scopes. A new node whose scope field is the zero value
+// (e.g. 0 or "") inherits the scope of its parent or target, so the zero value can not be used as a scope.
+func WithScopeColumns(fieldNames ...string) Option {
+	return func(options *treeOptions) {
+		options.scopeColumns = append(options.scopeColumns, fieldNames...)
+	}
+}
+
+// WithOrderInsertionBy keep siblings (and root trees) ordered by the given model fields,
+// CreateNode and moves to a new parent place the node accordingly instead of the last child.
+func WithOrderInsertionBy(fieldNames ...string) Option {
+	return func(options *treeOptions) {
+		options.orderInsertionBy = append(options.orderInsertionBy, fieldNames...)
+	}
+}
+
+// WithPromoteOrphans let the rebuilds turn orphans (nodes whose parent row does not exist)
+// into roots of new trees instead of returning *BrokenParentError. A parent_id cycle is
+// broken at its smallest node id the same way.
+func WithPromoteOrphans() Option {
+	return func(options *treeOptions) {
+		options.promoteOrphans = true
+	}
+}
+
+// WithLostAndFound let the rebuilds attach orphans and broken parent_id cycles under the
+// node nodeID instead of returning *BrokenParentError
+func WithLostAndFound(nodeID interface{}) Option {
+	return func(options *treeOptions) {
+		options.lostAndFoundID = nodeID
+	}
+}
+
 // NewTreeManager create mptt tree manager
 func NewTreeManager(db *gorm.DB, modelPtr interface{}, opts ...Option) (TreeManager, error) {
 	t := tree{
-		DB:   db,
+		DB:   managerDB(db),
 		node: modelPtr,
 	}
 	options := &treeOptions{
@@ -81,37 +191,68 @@ func NewTreeManager(db *gorm.DB, modelPtr interface{}, opts ...Option) (TreeMana
 	for _, opt := range opts {
 		opt(options)
 	}
-	err := t.Statement.ParseWithSpecialTableName(t.node, options.specialTableName)
+	// parse on a fresh statement, db may have been parsed with another model
+	stmt := &gorm.Statement{DB: db}
+	err := stmt.ParseWithSpecialTableName(t.node, options.specialTableName)
 	if err != nil {
 		return nil, err
 	}
 	t.fields = &KeyFields{
 		ID: KeyField{
 			Attr:  ColumnIDAttr,
-			Field: t.Statement.Schema.FieldsByName[options.keyColumns.IDFieldName],
+			Field: stmt.Schema.FieldsByName[options.keyColumns.IDFieldName],
 		},
 		Parent: KeyField{
 			Attr:  ColumnParentIDAttr,
-			Field: t.Statement.Schema.FieldsByName[options.keyColumns.ParentFieldName],
+			Field: stmt.Schema.FieldsByName[options.keyColumns.ParentFieldName],
 		},
 		Tree: KeyField{
 			Attr:  ColumnTreeIDAttr,
-			Field: t.Statement.Schema.FieldsByName[options.keyColumns.TreeIDFieldName],
+			Field: stmt.Schema.FieldsByName[options.keyColumns.TreeIDFieldName],
 		},
 		Left: KeyField{
 			Attr:  ColumnLeftAttr,
-			Field: t.Statement.Schema.FieldsByName[options.keyColumns.LeftFieldName],
+			Field: stmt.Schema.FieldsByName[options.keyColumns.LeftFieldName],
 		},
 		Right: KeyField{
 			Attr:  ColumnRightAttr,
-			Field: t.Statement.Schema.FieldsByName[options.keyColumns.RightFieldName],
+			Field: stmt.Schema.FieldsByName[options.keyColumns.RightFieldName],
 		},
 		Level: KeyField{
 			Attr:  ColumnLevelAttr,
-			Field: t.Statement.Schema.FieldsByName[options.keyColumns.LevelFieldName],
+			Field: stmt.Schema.FieldsByName[options.keyColumns.LevelFieldName],
 		},
 	}
-	t.tableName = t.Statement.Table
+	for _, name := range options.scopeColumns {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return nil, fmt.Errorf("%w: %s", UnknownFieldError, name)
+		}
+		t.scopes = append(t.scopes, KeyField{Field: field})
+	}
+	for _, name := range options.orderInsertionBy {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return nil, fmt.Errorf("%w: %s", UnknownFieldError, name)
+		}
+		t.orderFields = append(t.orderFields, KeyField{Field: field})
+	}
+	t.promoteOrphans = options.promoteOrphans
+	t.lostAndFoundID = options.lostAndFoundID
+	t.hooks = options.hooks
+	for _, field := range stmt.Schema.Fields {
+		if field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 {
+			t.autoTimeFields = append(t.autoTimeFields, KeyField{Field: field})
+		}
+		if field.FieldType == deletedAtType && t.deletedAt.Field == nil {
+			t.deletedAt = KeyField{Field: field}
+			t.softDeleteMode = options.softDeleteMode
+			if t.softDeleteMode == 0 {
+				t.softDeleteMode = SoftDeleteKeepSlots
+			}
+		}
+	}
+	t.tableName = stmt.Table
 	return &t, nil
 }
 
@@ -125,11 +266,7 @@ const (
 )
 
 func (t *tree) Node(node interface{}) TreeNode {
-	newTree := &tree{
-		DB:        t.DB,
-		node:      node,
-		tableName: t.tableName,
-		fields:    t.fields,
-	}
+	newTree := t.withDB(t.DB)
+	newTree.node = node
 	return newTree
 }
@@ -0,0 +1,124 @@
+package mptt
+
+import (
+	"strings"
+)
+
+// orderAfterSQL 生成按orderFields排序时，位于n之后的节点的查询条件，
+// 即 (f1 > ?) OR (f1 = ? AND f2 > ?) OR ...
+func (t *tree) orderAfterSQL(n interface{}) (string, []interface{}) {
+	var (
+		conds      = make([]string, 0, len(t.orderFields))
+		vars       []interface{}
+		equalSql   string
+		equalsVars []interface{}
+	)
+	for _, field := range t.orderFields {
+		col := t.Statement.Quote(field.DBName)
+		value := getFieldValue(t.context(), n, field)
+		conds = append(conds, "("+equalSql+col+" > ?)")
+		vars = append(append(vars, equalsVars...), value)
+		equalSql += col + " = ? AND "
+		equalsVars = append(equalsVars, value)
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", vars
+}
+
+// orderBySQL orderFields对应的排序语句
+func (t *tree) orderBySQL() string {
+	orders := make([]string, 0, len(t.orderFields))
+	for _, field := range t.orderFields {
+		orders = append(orders, t.Statement.Quote(field.DBName)+" ASC")
+	}
+	return strings.Join(orders, ", ")
+}
+
+// nextOrderedSibling 按orderFields查找n插入到parent下时应位于其左侧的兄弟节点，
+// parent为nil时在n所在scope的根节点中查找。未配置orderFields或n应为最后一个节点时返回nil
+func (t *tree) nextOrderedSibling(n, parent interface{}) (interface{}, error) {
+	if len(t.orderFields) == 0 {
+		return nil, nil
+	}
+	var (
+		emptyNode = reflectNew(t.node)
+		sibling   = reflectNew(t.node)
+		tx        = t.Model(emptyNode)
+	)
+	if parent == nil {
+		tx = tx.Where(t.parentCond(t.rootParentID())).
+			Scopes(t.scoped(n)).Order(t.colTree() + " ASC")
+	} else {
+		tx = tx.Where(t.colParent()+" = ?", t.getNodeID(parent)).
+			Scopes(t.scoped(parent)).Order(t.colLeft() + " ASC")
+	}
+	if id := t.getNodeID(n); !isEmpty(id) {
+		tx = tx.Where(t.colID()+" <> ?", id)
+	}
+	afterSql, afterVars := t.orderAfterSQL(n)
+	result := tx.Where(afterSql, afterVars...).Limit(1).Find(sibling)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return sibling, nil
+}
+
+// ReorderChildren 按WithOrderInsertionBy配置的字段，重新排列parent下的整棵子树
+func (t *tree) ReorderChildren(parent interface{}) error {
+	if len(t.orderFields) == 0 {
+		return OrderInsertionByNotSetError
+	}
+	if err := t.validateType(parent); err != nil {
+		return err
+	}
+	return t.transaction(func(tx *tree) error {
+		return tx.reorderChildren(parent)
+	})
+}
+
+func (t *tree) reorderChildren(parent interface{}) error {
+	if err := t.RefreshNode(parent); err != nil {
+		return err
+	}
+	children, err := t.findNodes(t.Model(reflectNew(t.node)).
+		Where(t.colParent()+" = ?", t.getNodeID(parent)).
+		Scopes(t.scoped(parent)).
+		Order(t.orderBySQL() + ", " + t.colLeft() + " ASC"))
+	if err != nil {
+		return err
+	}
+	for i, child := range children {
+		// 前面的移动可能已改变了child的位置
+		if err = t.RefreshNode(child); err != nil {
+			return err
+		}
+		if i == 0 {
+			if t.getLeft(child) != t.getLeft(parent)+1 {
+				err = t.withMoveHooks(child, parent, FirstChild, func() error {
+					return t.moveChildWithinTree(child, parent, FirstChild)
+				})
+			}
+		} else {
+			previous := children[i-1]
+			if err = t.RefreshNode(previous); err != nil {
+				return err
+			}
+			if t.getLeft(child) != t.getRight(previous)+1 {
+				err = t.withMoveHooks(child, previous, Right, func() error {
+					return t.moveChildWithinTree(child, previous, Right)
+				})
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, child := range children {
+		if t.getDescendantCount(child) == 0 {
+			continue
+		}
+		if err = t.reorderChildren(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
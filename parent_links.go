@@ -0,0 +1,214 @@
+package mptt
+
+// existingIDs nodes的id，以及nodes引用的、存在于表中的父节点id（父节点可能在未加载的树中），键为idKey
+func (t *tree) existingIDs(nodes []interface{}) (map[interface{}]struct{}, error) {
+	exists := make(map[interface{}]struct{}, len(nodes))
+	for _, node := range nodes {
+		exists[idKey(t.getNodeID(node))] = struct{}{}
+	}
+	var missing []interface{}
+	for _, node := range nodes {
+		if !t.isRootNode(node) {
+			if _, ok := exists[idKey(t.getParentID(node))]; !ok {
+				missing = append(missing, t.getParentID(node))
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return exists, nil
+	}
+	found, err := t.findNodes(t.slotRows(reflectNew(t.node)).Select(t.colID()).Where(t.colID()+" IN ?", missing))
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range found {
+		exists[idKey(t.getNodeID(node))] = struct{}{}
+	}
+	return exists, nil
+}
+
+// outsideAncestors 沿parent_id查找treeIDs的树中节点的、tree_id不在treeIDs中的祖先节点id
+func (t *tree) outsideAncestors(treeIDs []int) ([]interface{}, error) {
+	var (
+		result  []interface{}
+		loaded              = make(map[interface{}]struct{})
+		parents interface{} = t.slotRows(reflectNew(t.node)).Select(t.colParent()).Where(t.colTree()+" IN ?", treeIDs)
+	)
+	for {
+		found, err := t.findNodes(t.slotRows(reflectNew(t.node)).Select(t.colID(), t.colParent()).
+			Where(t.colID()+" IN (?)", parents).
+			Where(t.colTree()+" NOT IN ?", treeIDs))
+		if err != nil {
+			return nil, err
+		}
+		var ids []interface{}
+		for _, node := range found {
+			id := t.getNodeID(node)
+			if _, ok := loaded[idKey(id)]; ok {
+				continue
+			}
+			loaded[idKey(id)] = struct{}{}
+			result = append(result, id)
+			if !t.isRootNode(node) {
+				ids = append(ids, t.getParentID(node))
+			}
+		}
+		if len(ids) == 0 {
+			return result, nil
+		}
+		parents = ids
+	}
+}
+
+// findBrokenParents 查找给定的树（treeIDs为空时为全部数据）中的孤儿节点与parent_id环，没有时返回nil。
+// 给定treeIDs时也检查这些树的节点在其他树中的祖先节点
+func (t *tree) findBrokenParents(treeIDs []int) (*BrokenParentError, []interface{}, error) {
+	columns := []string{t.colID(), t.colParent(), t.colTree()}
+	for _, field := range t.scopes {
+		columns = append(columns, t.Statement.Quote(field.DBName))
+	}
+	query := t.slotRows(reflectNew(t.node)).Select(columns).Order(t.colID() + " ASC")
+	if len(treeIDs) > 0 {
+		// 环可能经过其他树中的节点，沿parent_id一并加载指定树之外的祖先节点
+		outside, err := t.outsideAncestors(treeIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(outside) > 0 {
+			query = query.Where("("+t.colTree()+" IN ? OR "+t.colID()+" IN ?)", treeIDs, outside)
+		} else {
+			query = query.Where(t.colTree()+" IN ?", treeIDs)
+		}
+	}
+	nodes, err := t.findNodes(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	exists, err := t.existingIDs(nodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		broken = &BrokenParentError{}
+		// 需要修正的节点：孤儿节点及每个环中id最小的节点
+		entries = make([]interface{}, 0)
+		byID    = make(map[interface{}]interface{}, len(nodes))
+		// 节点在按id排序的nodes中的位置
+		positions = make(map[interface{}]int, len(nodes))
+		// 0 未访问，1 在当前路径上，2 已访问
+		states = make(map[interface{}]int, len(nodes))
+	)
+	for i, node := range nodes {
+		byID[idKey(t.getNodeID(node))] = node
+		positions[idKey(t.getNodeID(node))] = i
+	}
+	for _, node := range nodes {
+		if t.isRootNode(node) {
+			continue
+		}
+		if _, ok := exists[idKey(t.getParentID(node))]; !ok {
+			broken.Orphans = append(broken.Orphans, t.getNodeID(node))
+			entries = append(entries, node)
+		}
+	}
+	for _, node := range nodes {
+		var path []interface{}
+		current := node
+		for current != nil && states[idKey(t.getNodeID(current))] == 0 {
+			states[idKey(t.getNodeID(current))] = 1
+			path = append(path, current)
+			if t.isRootNode(current) {
+				current = nil
+				break
+			}
+			// 父节点不在已加载的节点中时，其不会属于环
+			current = byID[idKey(t.getParentID(current))]
+		}
+		if current != nil && states[idKey(t.getNodeID(current))] == 1 {
+			var cycle []interface{}
+			entry := current
+			for i := len(path) - 1; i >= 0; i-- {
+				cycle = append([]interface{}{t.getNodeID(path[i])}, cycle...)
+				if path[i] == current {
+					break
+				}
+			}
+			// 环中最先被访问的节点不一定id最小
+			for _, id := range cycle {
+				if positions[idKey(id)] < positions[idKey(t.getNodeID(entry))] {
+					entry = byID[idKey(id)]
+				}
+			}
+			broken.Cycles = append(broken.Cycles, cycle)
+			entries = append(entries, entry)
+		}
+		for _, visited := range path {
+			states[idKey(t.getNodeID(visited))] = 2
+		}
+	}
+	if len(entries) == 0 {
+		return nil, nil, nil
+	}
+	return broken, entries, nil
+}
+
+// repairParents 在修正之前检查parent_id，按配置将孤儿节点及环提升为根节点或挂到lost and found节点下，
+// 未配置时返回*BrokenParentError。返回修正后需要额外修正的tree_id（即lost and found节点所在的树）
+func (t *tree) repairParents(treeIDs []int) ([]int, error) {
+	broken, entries, err := t.findBrokenParents(treeIDs)
+	if err != nil || broken == nil {
+		return nil, err
+	}
+	if !t.promoteOrphans && t.lostAndFoundID == nil {
+		return nil, broken
+	}
+	var extraTreeIDs []int
+	err = t.transaction(func(tx *tree) error {
+		var lostAndFound interface{}
+		if tx.lostAndFoundID != nil {
+			node, err := tx.getNodeByID(tx.lostAndFoundID)
+			if err != nil {
+				return err
+			}
+			lostAndFound = node
+			extraTreeIDs = append(extraTreeIDs, tx.getTreeID(node))
+		}
+		for _, entry := range entries {
+			if err := tx.context().Err(); err != nil {
+				return err
+			}
+			// 提升为根节点时保留原tree_id，修正时将作为新的树
+			parentID, treeID := tx.rootParentID(), tx.getTreeID(entry)
+			if len(treeIDs) > 0 && !containsTreeID(treeIDs, treeID) {
+				// 环经过的其他树中的节点，其所在的树也需要修正
+				extraTreeIDs = append(extraTreeIDs, treeID)
+			}
+			if lostAndFound != nil {
+				if !tx.sameScope(entry, lostAndFound) {
+					return ScopeMismatchError
+				}
+				parentID, treeID = tx.getNodeID(lostAndFound), tx.getTreeID(lostAndFound)
+			}
+			err := tx.slotRows(reflectNew(tx.node)).Where(tx.colID()+" = ?", tx.getNodeID(entry)).
+				Select(tx.colParent(), tx.colTree()).
+				Updates(map[string]interface{}{
+					tx.colParent(true): parentID,
+					tx.colTree(true):   treeID,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		// 例如lost and found节点本身是孤儿节点或在环中
+		stillBroken, _, err := tx.findBrokenParents(append(append([]int(nil), treeIDs...), extraTreeIDs...))
+		if err != nil {
+			return err
+		}
+		if stillBroken != nil {
+			return stillBroken
+		}
+		return nil
+	})
+	return extraTreeIDs, err
+}
@@ -0,0 +1,338 @@
+package mptt
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pluginSkipKey 插件自身执行的语句的ctx带有该值，以免再次进入插件的回调
+type pluginSkipKey struct{}
+
+var modelBaseType = reflect.TypeOf(ModelBase{})
+
+// Plugin gorm plugin keeping the mptt columns in sync when nodes are written with the plain
+// gorm API: db.Create goes through CreateNode, db.Save / db.Updates move the node when its
+// parent changed (the stored mptt columns always win over the values in memory), and
+// db.Delete goes through DeleteNode, i.e. the descendants are deleted too.
+// The parent and mptt columns are never written from the values of an update. Select and Omit
+// of a create are kept for the other columns; ON CONFLICT is only supported as DO NOTHING,
+// which skips the nodes whose primary key already exists.
+// Models embedding ModelBase are handled automatically, other models are added with Register.
+type Plugin struct {
+	mu      sync.RWMutex
+	options map[reflect.Type][]Option
+}
+
+// NewPlugin create the plugin, use it with db.Use(mptt.NewPlugin())
+func NewPlugin() *Plugin {
+	return &Plugin{options: make(map[reflect.Type][]Option)}
+}
+
+// Register handle modelPtr with the tree options, e.g. WithAttrs for a model without ModelBase
+func (p *Plugin) Register(modelPtr interface{}, opts ...Option) *Plugin {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.options[reflect.Indirect(reflect.ValueOf(modelPtr)).Type()] = opts
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return "gorm-mptt"
+}
+
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	create := db.Callback().Create()
+	if err := create.Replace("gorm:create", p.createCallback(create.Get("gorm:create"))); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("mptt:update", p.updateCallback); err != nil {
+		return err
+	}
+	remove := db.Callback().Delete()
+	return remove.Replace("gorm:delete", p.deleteCallback(remove.Get("gorm:delete")))
+}
+
+// manager statement的模型由插件处理时返回其tree，否则返回nil
+func (p *Plugin) manager(db *gorm.DB) (*tree, error) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil, nil
+	}
+	if skip, _ := db.Statement.Context.Value(pluginSkipKey{}).(bool); skip {
+		return nil, nil
+	}
+	modelType := db.Statement.Schema.ModelType
+	p.mu.RLock()
+	opts, ok := p.options[modelType]
+	p.mu.RUnlock()
+	if !ok && !embedsModelBase(modelType) {
+		return nil, nil
+	}
+	// 不复用当前statement的条件，模型的hooks只由当前statement执行一次
+	session := db.Session(&gorm.Session{
+		NewDB:       true,
+		Initialized: true,
+		Context:     context.WithValue(db.Statement.Context, pluginSkipKey{}, true),
+	}).Session(&gorm.Session{SkipHooks: true})
+	opts = append(append([]Option(nil), opts...), WithTableName(db.Statement.Table))
+	manager, err := NewTreeManager(session, reflect.New(modelType).Interface(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return manager.(*tree), nil
+}
+
+func embedsModelBase(modelType reflect.Type) bool {
+	for i := 0; i < modelType.NumField(); i++ {
+		if field := modelType.Field(i); field.Anonymous && field.Type == modelBaseType {
+			return true
+		}
+	}
+	return false
+}
+
+// statementNodes statement中的模型指针，db.Create(&list)时为多个
+func statementNodes(db *gorm.DB) []interface{} {
+	var (
+		nodes []interface{}
+		value = db.Statement.ReflectValue
+	)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			elem := value.Index(i)
+			if elem.Kind() == reflect.Ptr {
+				nodes = append(nodes, elem.Interface())
+			} else if elem.CanAddr() {
+				nodes = append(nodes, elem.Addr().Interface())
+			}
+		}
+	case reflect.Struct:
+		if value.CanAddr() {
+			nodes = append(nodes, value.Addr().Interface())
+		}
+	}
+	return nodes
+}
+
+func (p *Plugin) createCallback(original func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		manager, err := p.manager(db)
+		if err != nil {
+			_ = db.AddError(err)
+			return
+		}
+		if manager == nil {
+			original(db)
+			return
+		}
+		manager.createRow = rowCreator(manager, db.Statement)
+		onConflict, hasConflict := statementOnConflict(db.Statement)
+		for _, node := range statementNodes(db) {
+			if id := manager.getNodeID(node); hasConflict && !isEmpty(id) {
+				// 主键已存在时只支持ON CONFLICT DO NOTHING，其他冲突（如唯一索引）按插入失败处理
+				_, err = manager.getNodeByID(id)
+				if err == nil && onConflict.DoNothing {
+					continue
+				}
+				if err == nil {
+					err = UnsupportedOnConflictError
+				}
+				if err != gorm.ErrRecordNotFound {
+					_ = db.AddError(err)
+					return
+				}
+			}
+			if err = manager.CreateNode(node); err != nil {
+				_ = db.AddError(err)
+				return
+			}
+			db.RowsAffected++
+		}
+	}
+}
+
+// rowCreator 按原语句的Select、Omit写入新节点的行，mptt列总是写入，关联已由原语句保存
+func rowCreator(manager *tree, stmt *gorm.Statement) func(db *gorm.DB, n interface{}) error {
+	keyFields := manager.keyFields()
+	isKey := func(column string) bool {
+		for _, field := range keyFields {
+			if column == field.Name || column == field.DBName {
+				return true
+			}
+		}
+		return false
+	}
+	omits := []string{clause.Associations}
+	for _, column := range stmt.Omits {
+		if !isKey(column) {
+			omits = append(omits, column)
+		}
+	}
+	var selects []string
+	if len(stmt.Selects) > 0 {
+		selects = append(selects, stmt.Selects...)
+		for _, field := range keyFields {
+			selects = append(selects, field.DBName)
+		}
+	}
+	return func(db *gorm.DB, n interface{}) error {
+		if len(selects) > 0 {
+			db = db.Select(selects)
+		}
+		return db.Omit(omits...).Create(n).Error
+	}
+}
+
+// statementOnConflict 语句的ON CONFLICT子句
+func statementOnConflict(stmt *gorm.Statement) (clause.OnConflict, bool) {
+	if c, ok := stmt.Clauses["ON CONFLICT"]; ok {
+		if onConflict, ok := c.Expression.(clause.OnConflict); ok {
+			return onConflict, true
+		}
+	}
+	return clause.OnConflict{}, false
+}
+
+func (p *Plugin) updateCallback(db *gorm.DB) {
+	manager, err := p.manager(db)
+	if err != nil || manager == nil {
+		_ = db.AddError(err)
+		return
+	}
+	// mptt列及parent_id只由manager维护，不写入语句中的值
+	for _, field := range manager.keyFields() {
+		db.Statement.Omits = append(db.Statement.Omits, field.DBName)
+	}
+	var (
+		parentValue interface{}
+		changed     bool
+		nodes       = statementNodes(db)
+		selected    = parentSelected(db.Statement, manager.fields.Parent)
+	)
+	if values, ok := db.Statement.Dest.(map[string]interface{}); ok {
+		// db.Model(&node).Updates(map)、db.Model(&node).Update(column, value)
+		parentValue, changed = values[manager.fields.Parent.DBName]
+		if !changed {
+			parentValue, changed = values[manager.fields.Parent.Name]
+		}
+		if changed {
+			node := reflectNew(manager.node)
+			manager.setParentID(node, parentValue)
+			parentValue = manager.getParentID(node)
+		}
+	} else if values := updatingStruct(db.Statement); values != nil {
+		// db.Model(&node).Updates(&T{...})，只更新非零值或Select的列
+		parentValue = manager.getParentID(values)
+		changed = selected || !isEmpty(parentValue)
+	} else {
+		// db.Save(&node)、db.Updates(&node)，新值即节点自身
+		for _, node := range nodes {
+			parentID := manager.getParentID(node)
+			if !selected && isEmpty(parentID) {
+				continue
+			}
+			if isEmpty(manager.getNodeID(node)) {
+				_ = db.AddError(BulkParentUpdateError)
+				return
+			}
+			if err = manager.moveToParent(node, parentID); err != nil {
+				_ = db.AddError(err)
+				return
+			}
+		}
+		return
+	}
+	if !changed {
+		return
+	}
+	if len(nodes) != 1 || isEmpty(manager.getNodeID(nodes[0])) {
+		_ = db.AddError(BulkParentUpdateError)
+		return
+	}
+	_ = db.AddError(manager.moveToParent(nodes[0], parentValue))
+}
+
+// parentSelected parent列被Select，零值也会被更新
+func parentSelected(stmt *gorm.Statement, parent KeyField) bool {
+	for _, column := range stmt.Selects {
+		if column == "*" || column == parent.Name || column == parent.DBName {
+			return true
+		}
+	}
+	return false
+}
+
+// updatingStruct Updates(&T{...})中与Model不同的结构体，Save(&node)、Updates(&node)时返回nil
+func updatingStruct(stmt *gorm.Statement) interface{} {
+	dest := reflect.ValueOf(stmt.Dest)
+	switch {
+	case dest.Kind() == reflect.Struct && dest.Type() == stmt.Schema.ModelType:
+		values := reflect.New(dest.Type())
+		values.Elem().Set(dest)
+		return values.Interface()
+	case dest.Kind() == reflect.Ptr && dest.Elem().Kind() == reflect.Struct && dest.Elem().Type() == stmt.Schema.ModelType:
+		if model := reflect.ValueOf(stmt.Model); model.Kind() == reflect.Ptr && model.Pointer() == dest.Pointer() {
+			return nil
+		}
+		return dest.Interface()
+	}
+	return nil
+}
+
+func (p *Plugin) deleteCallback(original func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		manager, err := p.manager(db)
+		if err != nil {
+			_ = db.AddError(err)
+			return
+		}
+		if manager == nil {
+			original(db)
+			return
+		}
+		var ids []interface{}
+		for _, node := range statementNodes(db) {
+			if id := manager.getNodeID(node); !isEmpty(id) {
+				ids = append(ids, id)
+			}
+		}
+		where, hasWhere := db.Statement.Clauses["WHERE"]
+		if len(ids) == 0 && !hasWhere && !db.AllowGlobalUpdate {
+			_ = db.AddError(gorm.ErrMissingWhereClause)
+			return
+		}
+		err = manager.transaction(func(tx *tree) error {
+			query := tx.Model(reflectNew(tx.node)).Select(tx.colID()).Order(tx.colLeft() + " ASC")
+			if hasWhere {
+				query = query.Clauses(where.Expression)
+			}
+			if len(ids) > 0 {
+				query = query.Where(tx.colID()+" IN ?", ids)
+			}
+			nodes, err := tx.findNodes(query)
+			if err != nil {
+				return err
+			}
+			for _, node := range nodes {
+				// 已作为前面节点的子孙节点被删除
+				node, err = tx.getNodeByID(tx.getNodeID(node))
+				if err == gorm.ErrRecordNotFound {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				if err = tx.deleteNode(node, true); err != nil {
+					return err
+				}
+				db.RowsAffected++
+			}
+			return nil
+		})
+		_ = db.AddError(err)
+	}
+}
@@ -0,0 +1,169 @@
+package mptt
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TreeScopes gorm scopes filtering the rows of the tree table, e.g.
+// db.Scopes(manager.DescendantsOf(node, true)). The columns are qualified with the table name,
+// or with the alias given to TableAs, so the scopes also work when the tree table is joined.
+// The node arguments are read in memory, refresh them after the tree changed.
+type TreeScopes interface {
+	DescendantsOf(node interface{}, includeSelf bool) func(db *gorm.DB) *gorm.DB
+	AncestorsOf(node interface{}, includeSelf bool) func(db *gorm.DB) *gorm.DB
+	SiblingsOf(node interface{}, includeSelf bool) func(db *gorm.DB) *gorm.DB
+	ChildrenOf(node interface{}) func(db *gorm.DB) *gorm.DB
+	// LeavesOf the leaf descendants of node, all the leaf nodes when node is nil
+	LeavesOf(node interface{}) func(db *gorm.DB) *gorm.DB
+	AtLevel(level int) func(db *gorm.DB) *gorm.DB
+	Roots() func(db *gorm.DB) *gorm.DB
+}
+
+// treeScopes TreeScopes的实现，table为限定列名的表名或别名
+type treeScopes struct {
+	t     *tree
+	table string
+}
+
+// TableAs the TreeScopes for the tree table joined as alias,
+// e.g. db.Joins("JOIN category c ON c.id = product.category_id").Scopes(manager.TableAs("c").ChildrenOf(node))
+func (t *tree) TableAs(alias string) TreeScopes {
+	return &treeScopes{t: t, table: alias}
+}
+
+func (t *tree) DescendantsOf(node interface{}, includeSelf bool) func(db *gorm.DB) *gorm.DB {
+	return t.TableAs(t.tableName).DescendantsOf(node, includeSelf)
+}
+
+func (t *tree) AncestorsOf(node interface{}, includeSelf bool) func(db *gorm.DB) *gorm.DB {
+	return t.TableAs(t.tableName).AncestorsOf(node, includeSelf)
+}
+
+func (t *tree) SiblingsOf(node interface{}, includeSelf bool) func(db *gorm.DB) *gorm.DB {
+	return t.TableAs(t.tableName).SiblingsOf(node, includeSelf)
+}
+
+func (t *tree) ChildrenOf(node interface{}) func(db *gorm.DB) *gorm.DB {
+	return t.TableAs(t.tableName).ChildrenOf(node)
+}
+
+func (t *tree) LeavesOf(node interface{}) func(db *gorm.DB) *gorm.DB {
+	return t.TableAs(t.tableName).LeavesOf(node)
+}
+
+func (t *tree) AtLevel(level int) func(db *gorm.DB) *gorm.DB {
+	return t.TableAs(t.tableName).AtLevel(level)
+}
+
+func (t *tree) Roots() func(db *gorm.DB) *gorm.DB {
+	return t.TableAs(t.tableName).Roots()
+}
+
+func (s *treeScopes) column(name string) clause.Column {
+	return clause.Column{Table: s.table, Name: name}
+}
+
+// where 加上软删除的条件后返回scope：通过别名join时gorm不会为树表加上deleted_at IS NULL
+func (s *treeScopes) where(exprs ...clause.Expression) func(db *gorm.DB) *gorm.DB {
+	if s.t.softDeleted() {
+		exprs = append(exprs, clause.Eq{Column: s.column(s.t.deletedAt.DBName), Value: nil})
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause.And(exprs...))
+	}
+}
+
+// inTree node所在的树及scope
+func (s *treeScopes) inTree(node interface{}, exprs ...clause.Expression) []clause.Expression {
+	exprs = append(exprs, clause.Eq{Column: s.column(s.t.fields.Tree.DBName), Value: s.t.getTreeID(node)})
+	for _, field := range s.t.scopes {
+		exprs = append(exprs, clause.Eq{Column: s.column(field.DBName), Value: getFieldValue(s.t.context(), node, field)})
+	}
+	return exprs
+}
+
+// parentIs parent_id为parentID，parentID为nil时为IS NULL
+func (s *treeScopes) parentIs(parentID interface{}) clause.Expression {
+	return clause.Eq{Column: s.column(s.t.fields.Parent.DBName), Value: parentID}
+}
+
+func (s *treeScopes) DescendantsOf(node interface{}, includeSelf bool) func(db *gorm.DB) *gorm.DB {
+	var (
+		left  = s.column(s.t.fields.Left.DBName)
+		right = s.column(s.t.fields.Right.DBName)
+	)
+	if includeSelf {
+		return s.where(s.inTree(node,
+			clause.Gte{Column: left, Value: s.t.getLeft(node)},
+			clause.Lte{Column: right, Value: s.t.getRight(node)})...)
+	}
+	return s.where(s.inTree(node,
+		clause.Gt{Column: left, Value: s.t.getLeft(node)},
+		clause.Lt{Column: right, Value: s.t.getRight(node)})...)
+}
+
+func (s *treeScopes) AncestorsOf(node interface{}, includeSelf bool) func(db *gorm.DB) *gorm.DB {
+	var (
+		left  = s.column(s.t.fields.Left.DBName)
+		right = s.column(s.t.fields.Right.DBName)
+	)
+	if includeSelf {
+		return s.where(s.inTree(node,
+			clause.Lte{Column: left, Value: s.t.getLeft(node)},
+			clause.Gte{Column: right, Value: s.t.getRight(node)})...)
+	}
+	return s.where(s.inTree(node,
+		clause.Lt{Column: left, Value: s.t.getLeft(node)},
+		clause.Gt{Column: right, Value: s.t.getRight(node)})...)
+}
+
+func (s *treeScopes) SiblingsOf(node interface{}, includeSelf bool) func(db *gorm.DB) *gorm.DB {
+	exprs := []clause.Expression{s.parentIs(s.t.getParentID(node))}
+	for _, field := range s.t.scopes {
+		exprs = append(exprs, clause.Eq{Column: s.column(field.DBName), Value: getFieldValue(s.t.context(), node, field)})
+	}
+	if !includeSelf {
+		exprs = append(exprs, clause.Neq{Column: s.column(s.t.fields.ID.DBName), Value: s.t.getNodeID(node)})
+	}
+	return s.where(exprs...)
+}
+
+func (s *treeScopes) ChildrenOf(node interface{}) func(db *gorm.DB) *gorm.DB {
+	return s.where(s.inTree(node, s.parentIs(s.t.getNodeID(node)))...)
+}
+
+func (s *treeScopes) LeavesOf(node interface{}) func(db *gorm.DB) *gorm.DB {
+	var (
+		left   = s.column(s.t.fields.Left.DBName)
+		right  = s.column(s.t.fields.Right.DBName)
+		exprs  []clause.Expression
+		isLeaf clause.Expression = clause.Expr{SQL: "? - ? = 1", Vars: []interface{}{right, left}}
+	)
+	if s.t.softDeleteMode == SoftDeleteKeepSlots {
+		// 子节点可能都已被软删除
+		children := clause.Table{Name: s.t.tableName, Alias: "mptt_children"}
+		isLeaf = clause.Expr{
+			SQL: "NOT EXISTS (SELECT 1 FROM ? WHERE ? = ? AND ? IS NULL)",
+			Vars: []interface{}{children,
+				clause.Column{Table: children.Alias, Name: s.t.fields.Parent.DBName},
+				s.column(s.t.fields.ID.DBName),
+				clause.Column{Table: children.Alias, Name: s.t.deletedAt.DBName},
+			},
+		}
+	}
+	if node != nil {
+		exprs = s.inTree(node,
+			clause.Gt{Column: left, Value: s.t.getLeft(node)},
+			clause.Lt{Column: right, Value: s.t.getRight(node)})
+	}
+	return s.where(append(exprs, isLeaf)...)
+}
+
+func (s *treeScopes) AtLevel(level int) func(db *gorm.DB) *gorm.DB {
+	return s.where(clause.Eq{Column: s.column(s.t.fields.Level.DBName), Value: level})
+}
+
+func (s *treeScopes) Roots() func(db *gorm.DB) *gorm.DB {
+	return s.where(s.parentIs(s.t.rootParentID()))
+}
@@ -0,0 +1,93 @@
+package mptt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PrintTree 以├──、└──的形式逐行打印node及其子孙节点（node为nil时为所有的树），只查询一次并按lvl缩进。
+// label为nil时使用WithRenderFields的字段、Name字段或id，WithRenderNumbers时在label后打印lft、rght
+func (t *tree) PrintTree(w io.Writer, node interface{}, label func(node interface{}) string, opts ...RenderOption) error {
+	options := &renderOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if label == nil {
+		label = options.label
+	}
+	if label == nil {
+		fieldsLabel, err := t.fieldsLabel(options.fields)
+		if err != nil {
+			return err
+		}
+		label = fieldsLabel
+	}
+	nodes, err := t.exportNodes(node)
+	if err != nil {
+		return err
+	}
+
+	// last[i] nodes[i]是否为其父节点的最后一个子节点：倒序遍历时记录各层级后面是否还有兄弟节点
+	last := make([]bool, len(nodes))
+	var following []bool
+	for i := len(nodes) - 1; i >= 0; i-- {
+		depth := t.getLevel(nodes[i]) - 1
+		if depth < 0 {
+			depth = 0
+		}
+		for len(following) <= depth {
+			following = append(following, false)
+		}
+		last[i] = !following[depth]
+		following[depth] = true
+		// 更深层级的节点属于其他的父节点
+		following = following[:depth+1]
+		if depth == 0 {
+			following[0] = false
+		}
+	}
+
+	var (
+		writer    = bufio.NewWriter(w)
+		baseLevel int
+		// ancestors 各层级祖先节点是否为最后一个子节点，决定该列画│还是空白
+		ancestors []bool
+	)
+	for i, item := range nodes {
+		level := t.getLevel(item)
+		if i == 0 || t.getTreeID(item) != t.getTreeID(nodes[i-1]) || level < baseLevel {
+			baseLevel = level
+		}
+		depth := level - baseLevel
+		if depth > len(ancestors) {
+			depth = len(ancestors)
+		}
+		ancestors = ancestors[:depth]
+		var line strings.Builder
+		if depth > 0 {
+			for _, isLast := range ancestors[1:] {
+				if isLast {
+					line.WriteString("    ")
+				} else {
+					line.WriteString("│   ")
+				}
+			}
+			if last[i] {
+				line.WriteString("└── ")
+			} else {
+				line.WriteString("├── ")
+			}
+		}
+		line.WriteString(label(item))
+		if options.numbers {
+			fmt.Fprintf(&line, " (%d, %d)", t.getLeft(item), t.getRight(item))
+		}
+		if _, err = fmt.Fprintln(writer, line.String()); err != nil {
+			return err
+		}
+		ancestors = append(ancestors, last[i])
+	}
+	return writer.Flush()
+}
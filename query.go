@@ -15,8 +15,24 @@ func (t *tree) getDescendantCount(n interface{}) int {
 	return int(math.Floor(float64(t.getRight(n)-t.getLeft(n)-1) / 2))
 }
 
+// GetDescendantCount SoftDeleteKeepSlots时区间中包含被软删除的节点，需要查库统计。
+// 查询失败时记录错误日志，并按区间计算（包含被软删除的节点），以免误判为叶子节点
 func (t *tree) GetDescendantCount() int {
-	return t.getDescendantCount(t.node)
+	if t.softDeleteMode != SoftDeleteKeepSlots {
+		return t.getDescendantCount(t.node)
+	}
+	var count int64
+	err := t.Model(reflectNew(t.node)).
+		Where(t.replacePlaceholder("[tree_id] = ? AND [left] > ? AND [right] < ?"),
+			t.getTreeID(t.node),
+			t.getLeft(t.node),
+			t.getRight(t.node),
+		).Scopes(t.scoped(t.node)).Count(&count).Error
+	if err != nil {
+		t.Logger.Error(t.context(), "mptt: count descendants of node %v: %v", t.getNodeID(t.node), err)
+		return t.getDescendantCount(t.node)
+	}
+	return int(count)
 }
 
 func (t *tree) GetAncestors(outListPtr interface{}, ascending, includeSelf bool) error {
@@ -38,7 +54,7 @@ func (t *tree) GetAncestors(outListPtr interface{}, ascending, includeSelf bool)
 			t.getTreeID(t.node),
 			t.getLeft(t.node),
 			t.getRight(t.node),
-		).Order(order).Find(outListPtr).Error
+		).Scopes(t.scoped(t.node)).Order(order).Find(outListPtr).Error
 }
 
 func (t *tree) GetDescendants(outListPtr interface{}, includeSelf bool) error {
@@ -53,7 +69,7 @@ func (t *tree) GetDescendants(outListPtr interface{}, includeSelf bool) error {
 			t.getTreeID(t.node),
 			t.getLeft(t.node),
 			t.getRight(t.node),
-		).Order(t.colLeft() + " asc").Find(outListPtr).Error
+		).Scopes(t.scoped(t.node)).Order(t.colLeft() + " asc").Find(outListPtr).Error
 }
 
 func (t *tree) GetFamily(outListPtr interface{}) error {
@@ -70,7 +86,7 @@ func (t *tree) GetFamily(outListPtr interface{}) error {
 		right,
 		left,
 		right,
-	).Order(t.colLeft() + " ASC").Find(outListPtr).Error
+	).Scopes(t.scoped(t.node)).Order(t.colLeft() + " ASC").Find(outListPtr).Error
 }
 
 func (t *tree) GetChildren(outListPtr interface{}) error {
@@ -80,6 +96,7 @@ func (t *tree) GetChildren(outListPtr interface{}) error {
 			t.getTreeID(t.node),
 			t.getNodeID(t.node),
 		).
+		Scopes(t.scoped(t.node)).
 		Order(t.colLeft() + " asc").
 		Find(outListPtr).Error
 }
@@ -93,16 +110,26 @@ func (t *tree) GetLeafNodes(outListPtr interface{}) error {
 		right    = t.getRight(t.node)
 	)
 	whereSql := "[tree_id] = ? AND [left] > ? AND [right] < ?"
+	leafCond := gorm.Expr(rightCol + " - " + leftCol + " = 1")
+	if t.softDeleteMode == SoftDeleteKeepSlots {
+		// 子节点可能都已被软删除
+		leafCond = gorm.Expr("NOT EXISTS (SELECT 1 FROM " + t.getTableName() + " children WHERE " +
+			t.Statement.Quote("children."+t.fields.Parent.DBName) + " = " +
+			t.Statement.Quote(t.tableName+"."+t.fields.ID.DBName) + " AND " +
+			t.Statement.Quote("children."+t.deletedAt.DBName) + " IS NULL)")
+	}
 	return t.Model(reflectNew(t.node)).
 		Where(t.replacePlaceholder(whereSql), treeId, left, right).
-		Where(gorm.Expr(rightCol + " - " + leftCol + " = 1")).
+		Where(leafCond).
+		Scopes(t.scoped(t.node)).
 		Order(t.colLeft() + " asc").
 		Find(outListPtr).Error
 }
 
 func (t *tree) GetSiblings(outListPtr interface{}, includeSelf bool) error {
 	tx := t.Model(reflectNew(t.node)).
-		Where(t.colParent()+" = ?", t.getParentID(t.node))
+		Where(t.parentCond(t.getParentID(t.node))).
+		Scopes(t.scoped(t.node))
 	if !includeSelf {
 		tx = tx.Where(t.colID()+" <> ?", t.getNodeID(t.node))
 	}
@@ -110,14 +137,15 @@ func (t *tree) GetSiblings(outListPtr interface{}, includeSelf bool) error {
 }
 
 func (t *tree) nextSibling(tx *gorm.DB, node interface{}) *gorm.DB {
-	whereSql := t.replacePlaceholder("[parent_id] = ? AND [left] > ?")
-	return tx.Where(whereSql, t.getParentID(node), t.getRight(node)).
+	return tx.Where(t.parentCond(t.getParentID(node))).Where(t.colLeft()+" > ?", t.getRight(node)).
+		Scopes(t.scoped(node)).
 		Order(t.colLeft() + " asc")
 }
 
 func (t *tree) GetNextSibling(outPtr interface{}, conds ...interface{}) error {
 	if t.isRootNode(t.node) {
-		return t.Where(t.colTree()+"> ?", t.getTreeID(t.node)).First(outPtr, conds...).Error
+		return t.Where(t.colTree()+"> ?", t.getTreeID(t.node)).
+			Scopes(t.scoped(t.node)).First(outPtr, conds...).Error
 	}
 	return t.Scopes(func(tx *gorm.DB) *gorm.DB {
 		return t.nextSibling(tx, t.node)
@@ -125,14 +153,15 @@ func (t *tree) GetNextSibling(outPtr interface{}, conds ...interface{}) error {
 }
 
 func (t *tree) previousSibling(tx *gorm.DB, node interface{}) *gorm.DB {
-	whereSql := t.replacePlaceholder("[parent_id] = ? AND [right] < ?")
-	return tx.Where(whereSql, t.getParentID(node), t.getLeft(node)).
+	return tx.Where(t.parentCond(t.getParentID(node))).Where(t.colRight()+" < ?", t.getLeft(node)).
+		Scopes(t.scoped(node)).
 		Order(t.colRight() + " desc")
 }
 
 func (t *tree) GetPreviousSibling(outPtr interface{}, conds ...interface{}) error {
 	if t.isRootNode(t.node) {
-		return t.Where(t.colTree()+"< ?", t.getTreeID(t.node)).First(outPtr, conds...).Error
+		return t.Where(t.colTree()+"< ?", t.getTreeID(t.node)).
+			Scopes(t.scoped(t.node)).First(outPtr, conds...).Error
 	}
 	return t.Scopes(func(tx *gorm.DB) *gorm.DB {
 		return t.previousSibling(tx, t.node)
@@ -140,7 +169,7 @@ func (t *tree) GetPreviousSibling(outPtr interface{}, conds ...interface{}) erro
 }
 
 func (t *tree) GetRoot(outPtr interface{}) error {
-	return t.RootNode(t.getTreeID(t.node), outPtr)
+	return t.rootNode(t.node, t.getTreeID(t.node), outPtr)
 }
 
 func (t *tree) GetLevel() int {
@@ -160,11 +189,12 @@ func (t *tree) IsRootNode() bool {
 }
 
 func (t *tree) isRootNode(n interface{}) bool {
-	return isEmpty(t.getParentID(n))
+	parentID := t.getParentID(n)
+	return parentID == nil || isEmpty(parentID)
 }
 
 func (t *tree) IsLeafNode() bool {
-	return t.getDescendantCount(t.node) == 0
+	return t.GetDescendantCount() == 0
 }
 
 func (t *tree) IsDescendantOf(other interface{}, includeSelf bool) bool {
@@ -196,12 +226,15 @@ func (t *tree) IsAncestorOf(other interface{}, includeSelf bool) bool {
 }
 
 func (t *tree) RootNodes(outListPtr interface{}) error {
-	emptyNode := reflectNew(t.node)
-	return t.Model(emptyNode).Where(t.colParent()+" = ?", t.getParentID(emptyNode)).Find(outListPtr).Error
+	return t.Model(reflectNew(t.node)).Where(t.parentCond(t.rootParentID())).Find(outListPtr).Error
 }
 
 func (t *tree) RootNode(treeID int, outPtr interface{}) error {
-	emptyNode := reflectNew(t.node)
-	whereSql := t.replacePlaceholder("[parent_id] = ? AND [tree_id] = ?")
-	return t.Model(emptyNode).Where(whereSql, t.getParentID(emptyNode), treeID).Find(outPtr).Error
+	return t.rootNode(nil, treeID, outPtr)
+}
+
+// rootNode 查询scopeNode所在scope中的根节点，scopeNode为nil时不限制scope
+func (t *tree) rootNode(scopeNode interface{}, treeID int, outPtr interface{}) error {
+	return t.Model(reflectNew(t.node)).Where(t.parentCond(t.rootParentID())).Where(t.colTree()+" = ?", treeID).
+		Scopes(t.scoped(scopeNode)).Find(outPtr).Error
 }
@@ -1,44 +1,73 @@
 package mptt
 
 import (
+	"strings"
+
 	"gorm.io/gorm"
 )
 
-// Rebuild 全部数据修正
-func (t *tree) Rebuild() error {
-	var existsTreeIds []int
+// rootsQuery 查询根节点的id、tree_id及scope列，按scope、tree_id排序
+func (t *tree) rootsQuery() *gorm.DB {
 	emptyNode := reflectNew(t.node)
-	err := t.Model(emptyNode).Select(t.colTree()).Order(t.colTree()+" ASC").
-		Where(t.colParent()+" = ?", t.getParentID(emptyNode)).Scan(&existsTreeIds).Error
+	columns := []string{t.colID(), t.colTree(), t.colParent()}
+	orders := make([]string, 0, len(t.scopes)+2)
+	for _, field := range t.scopes {
+		columns = append(columns, t.Statement.Quote(field.DBName))
+		orders = append(orders, t.Statement.Quote(field.DBName)+" ASC")
+	}
+	orders = append(orders, t.colTree()+" ASC", t.colID()+" ASC")
+	return t.slotRows(emptyNode).Select(columns).
+		Where(t.parentCond(t.rootParentID())).
+		Order(strings.Join(orders, ", "))
+}
+
+// Rebuild 全部数据修正，修正前检查孤儿节点与parent_id环，见WithPromoteOrphans、WithLostAndFound
+func (t *tree) Rebuild() error {
+	if _, err := t.repairParents(nil); err != nil {
+		return err
+	}
+	roots, err := t.findNodes(t.rootsQuery())
 	if err != nil {
 		return err
 	}
 
-	lastTreeId := 0
-	for _, treeId := range existsTreeIds {
-		if lastTreeId != 0 && treeId == lastTreeId {
-			continue
+	// 按scope和tree_id分组修正
+	for start := 0; start < len(roots); {
+		if err = t.context().Err(); err != nil {
+			return err
+		}
+		end := start + 1
+		for end < len(roots) && t.sameScope(roots[start], roots[end]) &&
+			t.getTreeID(roots[start]) == t.getTreeID(roots[end]) {
+			end++
 		}
-		err = t.PartialRebuild(treeId)
+		err = t.partialRebuild(roots[start:end], t.getTreeID(roots[start]))
 		if err != nil {
 			return err
 		}
-		existsTreeIds = append(existsTreeIds, treeId)
-		lastTreeId = treeId
+		start = end
 	}
 
-	err = t.Model(emptyNode).Select(t.colTree()).Order(t.colTree()+" ASC").
-		Where(t.colParent()+" = ?", t.getParentID(emptyNode)).Scan(&existsTreeIds).Error
+	roots, err = t.findNodes(t.rootsQuery())
 	if err != nil {
 		return err
 	}
 
+	// 每个scope中的tree_id从1开始连续
 	expectTreeId := 1
-	for _, treeId := range existsTreeIds {
+	for i, root := range roots {
+		if i > 0 && !t.sameScope(roots[i-1], root) {
+			expectTreeId = 1
+		}
+		treeId := t.getTreeID(root)
 		diff := treeId - expectTreeId
 		if diff != 0 {
-			err = t.Model(emptyNode).Select(t.colTree()).Where(t.colTree()+" = ?", treeId).
-				Update(t.colTree(), gorm.Expr(t.colTree()+" - ?", diff)).Error
+			if err = t.context().Err(); err != nil {
+				return err
+			}
+			err = t.slotRows(reflectNew(t.node)).Where(t.colTree()+" = ?", treeId).
+				Scopes(t.scoped(root)).
+				Update(t.colTree(true), gorm.Expr(t.colTree()+" - ?", diff)).Error
 			if err != nil {
 				return err
 			}
@@ -48,54 +77,98 @@ func (t *tree) Rebuild() error {
 	return nil
 }
 
-// PartialRebuild 当一棵树的秩序混乱了时，需要根据parent_id关系对树进行修正
+// PartialRebuild 当一棵树的秩序混乱了时，需要根据parent_id关系对树进行修正。
+// 配置了scope时，将修正所有scope中tree_id为treeID的树
 func (t *tree) PartialRebuild(treeID int) error {
-	var rootPks []interface{} // 有可能创建了多个相同treeId的根节点
-	emptyNode := reflectNew(t.node)
-	whereSql := t.replacePlaceholder("[parent_id] = ? AND [tree_id] = ?")
-	err := t.Model(emptyNode).Select(t.colID()).
-		Where(whereSql, t.getParentID(emptyNode), treeID).Scan(&rootPks).Error
+	extraTreeIDs, err := t.repairParents([]int{treeID})
 	if err != nil {
 		return err
 	}
-	if len(rootPks) == 0 {
-		return nil
+	if err = t.partialRebuildTree(treeID); err != nil {
+		return err
 	}
-	if treeID == 0 {
-		treeID = t.getNextTreeId()
+	// 孤儿节点被挂到了lost and found节点所在的树
+	for _, extraTreeID := range extraTreeIDs {
+		if extraTreeID == treeID {
+			continue
+		}
+		if err = t.partialRebuildTree(extraTreeID); err != nil {
+			return err
+		}
 	}
-	_, err = t.rebuildHelper(rootPks[0], 1, treeID, 1)
+	return nil
+}
+
+// partialRebuildTree 修正所有scope中tree_id为treeID的树
+func (t *tree) partialRebuildTree(treeID int) error {
+	roots, err := t.findNodes(t.rootsQuery().Where(t.colTree()+" = ?", treeID))
 	if err != nil {
 		return err
 	}
-	if len(rootPks) == 1 {
+	for start := 0; start < len(roots); {
+		end := start + 1
+		for end < len(roots) && t.sameScope(roots[start], roots[end]) {
+			end++
+		}
+		if err = t.partialRebuild(roots[start:end], treeID); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// partialRebuild 修正同一scope中的一棵树，roots为该树的根节点
+// 有可能创建了多个相同treeId的根节点，除第一个外都会被修正为新的树
+func (t *tree) partialRebuild(roots []interface{}, treeID int) error {
+	if len(roots) == 0 {
 		return nil
 	}
-	for _, rootPk := range rootPks[1:] {
-		newTreeId := t.getNextTreeId()
-		_, err = t.rebuildHelper(rootPk, 1, newTreeId, 1)
+	var err error
+	if treeID == 0 {
+		if treeID, err = t.getNextTreeId(roots[0]); err != nil {
+			return err
+		}
+	}
+	if _, err = t.rebuildHelper(t.getNodeID(roots[0]), 1, treeID, 1); err != nil {
+		return err
+	}
+	for _, root := range roots[1:] {
+		newTreeId, err := t.getNextTreeId(root)
+		if err != nil {
+			return err
+		}
+		if _, err = t.rebuildHelper(t.getNodeID(root), 1, newTreeId, 1); err != nil {
+			return err
+		}
 	}
-	return err
+	return nil
 }
 
 // 递归一个个修正，效率会很低，但是能确保正确性
 func (t *tree) rebuildHelper(pk interface{}, left, treeId, level int) (int, error) {
+	// 在每条语句之前检查ctx是否已取消
+	if err := t.context().Err(); err != nil {
+		return 0, err
+	}
 	right := left + 1
-	var children []int
 	emptyNode := reflectNew(t.node)
-	// 以原有lft为序修正Tree
-	err := t.Model(emptyNode).
-		Select(t.colID()).Where(t.colParent()+" = ?", pk).Order(t.colLeft() + " ASC").Scan(&children).Error
+	// 以原有lft为序修正Tree，子节点以模型加载，id可以为任意类型
+	children, err := t.findNodes(t.slotRows(emptyNode).
+		Select(t.colID()).Where(t.parentCond(pk)).Order(t.colLeft() + " ASC"))
 	if err != nil {
 		return 0, err
 	}
 	for _, child := range children {
-		right, err = t.rebuildHelper(child, right, treeId, level+1)
+		right, err = t.rebuildHelper(t.getNodeID(child), right, treeId, level+1)
 		if err != nil {
 			return right + 1, err
 		}
 	}
-	err = t.Model(emptyNode).Where(t.colID()+" = ?", pk).
+	if err = t.context().Err(); err != nil {
+		return 0, err
+	}
+	err = t.slotRows(emptyNode).Where(t.colID()+" = ?", pk).
 		Select(t.colTree(), t.colLeft(), t.colRight(), t.colLevel()).
 		Updates(map[string]interface{}{
 			t.colTree(true):  treeId,
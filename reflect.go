@@ -1,6 +1,7 @@
 package mptt
 
 import (
+	"fmt"
 	"reflect"
 )
 
@@ -27,6 +28,15 @@ func reflectNew(target interface{}) interface{} {
 	return newStruc.Interface()
 }
 
+// modelSlice 将节点转换为[]*Model
+func modelSlice(modelPtr interface{}, nodes []interface{}) interface{} {
+	list := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(reflectNew(modelPtr))), 0, len(nodes))
+	for _, node := range nodes {
+		list = reflect.Append(list, reflect.ValueOf(node))
+	}
+	return list.Interface()
+}
+
 // isEmpty only for mptt key field
 func isEmpty(object interface{}) bool {
 	if object == nil {
@@ -42,6 +52,9 @@ func isEmpty(object interface{}) bool {
 		return reflect.ValueOf(object).String() == ""
 	case reflect.Float32, reflect.Float64:
 		return reflect.ValueOf(object).Float() == 0
+	case reflect.Array:
+		// e.g. [16]byte, uuid.UUID
+		return reflect.ValueOf(object).IsZero()
 	case reflect.Struct:
 		return reflect.DeepEqual(object, reflect.New(fieldType).Elem().Interface())
 	default:
@@ -78,3 +91,11 @@ func (t *tree) equalIDValue(ida, idb interface{}) bool {
 	}
 	return reflect.DeepEqual(ida, idb)
 }
+
+// idKey 作为map键的id，不可比较的类型（如[]byte）使用其字符串形式
+func idKey(id interface{}) interface{} {
+	if id == nil || reflect.TypeOf(id).Comparable() {
+		return id
+	}
+	return fmt.Sprint(id)
+}
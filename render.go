@@ -0,0 +1,241 @@
+package mptt
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type renderOptions struct {
+	label     func(node interface{}) string
+	fields    []string
+	numbers   bool
+	integrity bool
+}
+
+// RenderOption options of RenderDOT, RenderMermaid and PrintTree
+type RenderOption func(options *renderOptions)
+
+// WithRenderLabel the label of each node, default is the Name field, or the id when the model has no Name
+func WithRenderLabel(label func(node interface{}) string) RenderOption {
+	return func(options *renderOptions) {
+		options.label = label
+	}
+}
+
+// WithRenderFields label each node with the values of the fields joined by spaces
+func WithRenderFields(names ...string) RenderOption {
+	return func(options *renderOptions) {
+		options.fields = names
+	}
+}
+
+// WithRenderNumbers add the lft, rght and lvl values to the labels
+func WithRenderNumbers() RenderOption {
+	return func(options *renderOptions) {
+		options.numbers = true
+	}
+}
+
+// WithRenderIntegrity validate the rendered trees and highlight the nodes with violations,
+// their labels list the violation kinds
+func WithRenderIntegrity() RenderOption {
+	return func(options *renderOptions) {
+		options.integrity = true
+	}
+}
+
+type renderNode struct {
+	key        string
+	label      string
+	violations []ViolationKind
+}
+
+type renderEdge struct {
+	from, to string
+	// parentLink parent_id与lft、rght的嵌套关系不一致时，parent_id指向的节点
+	parentLink bool
+}
+
+type renderGraph struct {
+	nodes []renderNode
+	edges []renderEdge
+}
+
+// renderGraph 按lft、rght的嵌套关系组装root（为nil时为所有的树）的节点和边，
+// parent_id与之不一致时另外加上parent_id的边
+func (t *tree) renderGraph(root interface{}, opts []RenderOption) (*renderGraph, error) {
+	options := &renderOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.label == nil {
+		label, err := t.fieldsLabel(options.fields)
+		if err != nil {
+			return nil, err
+		}
+		options.label = label
+	}
+	nodes, err := t.exportNodes(root)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := make(map[interface{}][]ViolationKind)
+	if options.integrity && len(nodes) > 0 {
+		var treeIDs []int
+		for _, node := range nodes {
+			if len(treeIDs) == 0 || treeIDs[len(treeIDs)-1] != t.getTreeID(node) {
+				treeIDs = append(treeIDs, t.getTreeID(node))
+			}
+		}
+		report, err := t.Validate(treeIDs...)
+		if err != nil {
+			return nil, err
+		}
+		kinds := make([]string, 0, len(report.Violations))
+		for kind := range report.Violations {
+			kinds = append(kinds, string(kind))
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			for _, id := range report.Violations[ViolationKind(kind)] {
+				violations[id] = append(violations[id], ViolationKind(kind))
+			}
+		}
+	}
+
+	var (
+		graph = &renderGraph{}
+		keys  = make(map[interface{}]string, len(nodes))
+		stack []interface{}
+	)
+	for i, node := range nodes {
+		id := t.getNodeID(node)
+		keys[id] = fmt.Sprintf("n%d", i)
+		label := options.label(node)
+		if options.numbers {
+			label += fmt.Sprintf("\n%d-%d lvl %d", t.getLeft(node), t.getRight(node), t.getLevel(node))
+		}
+		graph.nodes = append(graph.nodes, renderNode{key: keys[id], label: label, violations: violations[id]})
+	}
+	for _, node := range nodes {
+		for len(stack) > 0 && !t.inSubtree(stack[len(stack)-1], node) {
+			stack = stack[:len(stack)-1]
+		}
+		var nested interface{}
+		if len(stack) > 0 {
+			nested = stack[len(stack)-1]
+			graph.edges = append(graph.edges, renderEdge{from: keys[t.getNodeID(nested)], to: keys[t.getNodeID(node)]})
+		}
+		// parent_id指向未渲染的节点时省略
+		if !t.isRootNode(node) {
+			parentID := t.getParentID(node)
+			from, ok := keys[parentID]
+			if ok && (nested == nil || !t.equalIDValue(parentID, t.getNodeID(nested))) {
+				graph.edges = append(graph.edges, renderEdge{from: from, to: keys[t.getNodeID(node)], parentLink: true})
+			}
+		}
+		stack = append(stack, node)
+	}
+	return graph, nil
+}
+
+// fieldsLabel 以fields的值作为节点的label，fields为空时使用Name字段或id
+func (t *tree) fieldsLabel(names []string) (func(node interface{}) string, error) {
+	if len(names) == 0 {
+		if _, err := t.lookUpFields("Name"); err != nil {
+			return func(node interface{}) string {
+				return fmt.Sprint(t.getNodeID(node))
+			}, nil
+		}
+		names = []string{"Name"}
+	}
+	fields, err := t.lookUpFields(names...)
+	if err != nil {
+		return nil, err
+	}
+	return func(node interface{}) string {
+		values := make([]string, 0, len(fields))
+		for _, field := range fields {
+			values = append(values, t.fieldText(node, field))
+		}
+		return strings.Join(values, " ")
+	}, nil
+}
+
+// RenderDOT 将root及其子孙节点（root为nil时为所有的树）写为graphviz的DOT格式，
+// 虚线为与lft、rght嵌套关系不一致的parent_id
+func (t *tree) RenderDOT(w io.Writer, root interface{}, opts ...RenderOption) error {
+	graph, err := t.renderGraph(root, opts)
+	if err != nil {
+		return err
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	var b strings.Builder
+	b.WriteString("digraph tree {\n\tnode [shape=box];\n")
+	for _, node := range graph.nodes {
+		label := node.label
+		if len(node.violations) > 0 {
+			label += "\n" + joinKinds(node.violations)
+			fmt.Fprintf(&b, "\t%s [label=\"%s\", color=red, style=filled, fillcolor=\"#ffd6d6\"];\n", node.key, escape.Replace(label))
+		} else {
+			fmt.Fprintf(&b, "\t%s [label=\"%s\"];\n", node.key, escape.Replace(label))
+		}
+	}
+	for _, edge := range graph.edges {
+		if edge.parentLink {
+			fmt.Fprintf(&b, "\t%s -> %s [style=dashed, color=red, label=\"parent_id\"];\n", edge.from, edge.to)
+		} else {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", edge.from, edge.to)
+		}
+	}
+	b.WriteString("}\n")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// RenderMermaid 将root及其子孙节点（root为nil时为所有的树）写为mermaid的flowchart，
+// 虚线为与lft、rght嵌套关系不一致的parent_id
+func (t *tree) RenderMermaid(w io.Writer, root interface{}, opts ...RenderOption) error {
+	graph, err := t.renderGraph(root, opts)
+	if err != nil {
+		return err
+	}
+	escape := strings.NewReplacer(`"`, "#quot;", "\n", "<br/>")
+	var (
+		b       strings.Builder
+		flagged []string
+	)
+	b.WriteString("graph TD\n")
+	for _, node := range graph.nodes {
+		label := node.label
+		if len(node.violations) > 0 {
+			label += "\n" + joinKinds(node.violations)
+			flagged = append(flagged, node.key)
+		}
+		fmt.Fprintf(&b, "\t%s[\"%s\"]\n", node.key, escape.Replace(label))
+	}
+	for _, edge := range graph.edges {
+		if edge.parentLink {
+			fmt.Fprintf(&b, "\t%s -.->|parent_id| %s\n", edge.from, edge.to)
+		} else {
+			fmt.Fprintf(&b, "\t%s --> %s\n", edge.from, edge.to)
+		}
+	}
+	if len(flagged) > 0 {
+		b.WriteString("\tclassDef violation fill:#ffd6d6,stroke:#d00\n")
+		fmt.Fprintf(&b, "\tclass %s violation\n", strings.Join(flagged, ","))
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func joinKinds(kinds []ViolationKind) string {
+	names := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		names = append(names, string(kind))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,71 @@
+package mptt
+
+import (
+	"gorm.io/gorm"
+)
+
+// SaveNode 保存节点。节点的ParentID与数据库中的不同时，先将节点（及其子孙节点）移动为新父节点的最后一个子节点，
+// 配置了WithOrderInsertionBy时按排序字段移动；再在同一事务中保存其余的非MPTT列。
+// 节点不存在时等同于CreateNode
+func (t *tree) SaveNode(n interface{}) error {
+	if err := t.validateType(n); err != nil {
+		return err
+	}
+	return t.transaction(func(tx *tree) error {
+		if id := tx.getNodeID(n); isEmpty(id) {
+			return tx.createNode(n)
+		} else if _, err := tx.getNodeByID(id); err == gorm.ErrRecordNotFound {
+			return tx.createNode(n)
+		} else if err != nil {
+			return err
+		}
+		if err := tx.moveToParent(n, tx.getParentID(n)); err != nil {
+			return err
+		}
+		return tx.Omit(tx.colParent(true), tx.colTree(true), tx.colLeft(true),
+			tx.colRight(true), tx.colLevel(true)).Save(n).Error
+	})
+}
+
+// moveToParent 当parentID与数据库中的不同时将节点移动为其最后一个子节点（或按排序字段），并将数据库中的mptt列写回node。
+// node在数据库中不存在时不做处理
+func (t *tree) moveToParent(node, parentID interface{}) error {
+	return t.transaction(func(tx *tree) error {
+		stored, err := tx.getNodeByID(tx.getNodeID(node))
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		storedParentID := tx.getParentID(stored)
+		if parentID != nil && isEmpty(parentID) {
+			parentID = nil
+		}
+		if storedParentID != nil && isEmpty(storedParentID) {
+			storedParentID = nil
+		}
+		if !tx.equalIDValue(storedParentID, parentID) {
+			// 按将要保存的排序字段确定位置
+			for _, field := range tx.orderFields {
+				setFieldValue(tx.context(), stored, field, getFieldValue(tx.context(), node, field))
+			}
+			var target interface{}
+			if parentID != nil {
+				if target, err = tx.getNodeByID(parentID); err != nil {
+					return err
+				}
+			}
+			if err = tx.moveNode(stored, target, LastChild); err != nil {
+				return err
+			}
+			if err = tx.RefreshNode(stored); err != nil {
+				return err
+			}
+		}
+		for _, field := range []KeyField{tx.fields.Parent, tx.fields.Tree, tx.fields.Left, tx.fields.Right, tx.fields.Level} {
+			setFieldValue(tx.context(), node, field, getFieldValue(tx.context(), stored, field))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,209 @@
+package mptt
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// SoftDeleteMode how DeleteNode treats models with a gorm.DeletedAt field
+type SoftDeleteMode int
+
+const (
+	// SoftDeleteKeepSlots the soft-deleted subtree keeps its lft/rght slots (and tree_id for a root),
+	// the queries exclude it. Default for models with a gorm.DeletedAt field
+	SoftDeleteKeepSlots SoftDeleteMode = iota + 1
+	// SoftDeleteArchive the soft-deleted subtree is detached into an archive tree with a negative
+	// tree_id, the gap in the live tree is closed
+	SoftDeleteArchive
+	// SoftDeleteDisabled the rows are deleted permanently even if the model has a gorm.DeletedAt field
+	SoftDeleteDisabled
+)
+
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// WithSoftDelete set how the soft-deleted subtrees are kept, see SoftDeleteMode
+func WithSoftDelete(mode SoftDeleteMode) Option {
+	return func(options *treeOptions) {
+		options.softDeleteMode = mode
+	}
+}
+
+// softDeleted 节点被软删除（而非物理删除）
+func (t *tree) softDeleted() bool {
+	return t.softDeleteMode == SoftDeleteKeepSlots || t.softDeleteMode == SoftDeleteArchive
+}
+
+// slotRows 占用树中位置的行，SoftDeleteKeepSlots时包括被软删除的行，用于修正、校验及tree_id的维护
+func (t *tree) slotRows(model interface{}) *gorm.DB {
+	if t.softDeleteMode == SoftDeleteKeepSlots {
+		return t.Model(model).Unscoped()
+	}
+	return t.Model(model)
+}
+
+// deleteRows 删除用的语句，SoftDeleteDisabled时物理删除
+func (t *tree) deleteRows(model interface{}) *gorm.DB {
+	if t.softDeleteMode == SoftDeleteDisabled {
+		return t.Model(model).Unscoped()
+	}
+	return t.Model(model)
+}
+
+// getArchiveTreeId n所在scope中的下一个归档树的tree_id，从-1开始递减
+func (t *tree) getArchiveTreeId(n interface{}) (int, error) {
+	var treeIds []int
+	err := t.Model(reflectNew(t.node)).Unscoped().Scopes(t.scoped(n)).
+		Order(t.colTree()+" ASC").Limit(1).Pluck(t.colTree(true), &treeIds).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(treeIds) == 0 || treeIds[0] >= 0 {
+		return -1, nil
+	}
+	return treeIds[0] - 1, nil
+}
+
+// archiveSubtree 将realNode及其子孙节点移动到新的归档树并关闭原树中的空隙，保留realNode的parent_id以便恢复
+func (t *tree) archiveSubtree(realNode interface{}) (int, error) {
+	var (
+		treeID = t.getTreeID(realNode)
+		left   = t.getLeft(realNode)
+		right  = t.getRight(realNode)
+		offset = left - 1
+	)
+	archiveTreeID, err := t.getArchiveTreeId(realNode)
+	if err != nil {
+		return 0, err
+	}
+	updateSQL := t.replacePlaceholder(`UPDATE [table_tree] SET
+        [level] = [level] - ?,
+        [tree_id] = ?,
+        [left] = [left] - ?,
+        [right] = [right] - ?
+        WHERE [tree_id] = ? AND [left] >= ? AND [left] <= ?`)
+	err = t.execInScope(realNode, updateSQL,
+		t.getLevel(realNode)-1,
+		archiveTreeID,
+		offset,
+		offset,
+		treeID,
+		left,
+		right,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if t.isRootNode(realNode) {
+		return archiveTreeID, t.Model(reflectNew(t.node)).
+			Where(t.colTree()+" > ?", treeID).
+			Scopes(t.scoped(realNode)).
+			Update(t.colTree(true), gorm.Expr(t.colTree()+" - 1")).Error
+	}
+	return archiveTreeID, t.closeGap(realNode, right-left+1, right, treeID)
+}
+
+// RestoreNode re-insert the soft-deleted subtree of n as the last child of its old parent when
+// targetPtr is nil, or at the position relative to targetPtr
+func (t *tree) RestoreNode(n, targetPtr interface{}, position PositionEnum) error {
+	if err := t.validateType(n); err != nil {
+		return err
+	}
+	if !t.softDeleted() {
+		return SoftDeleteNotEnabledError
+	}
+	return t.transaction(func(tx *tree) error {
+		stored := reflectNew(tx.node)
+		err := tx.Model(stored).Unscoped().Where(tx.colID()+" = ?", tx.getNodeID(n)).First(stored).Error
+		if err != nil {
+			return err
+		}
+		deletedAt, _ := getFieldValue(tx.context(), stored, tx.deletedAt).(gorm.DeletedAt)
+		if !deletedAt.Valid {
+			return NodeNotDeletedError
+		}
+
+		var target interface{}
+		if targetPtr != nil {
+			if target, err = tx.getNodeByID(tx.getNodeID(targetPtr)); err != nil {
+				return err
+			}
+		} else if !tx.isRootNode(stored) {
+			position = LastChild
+			target, err = tx.getNodeByID(tx.getParentID(stored))
+			if err == gorm.ErrRecordNotFound {
+				return DeletedParentError
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// 只恢复同一次删除的行，之前被单独删除的子孙节点仍保持删除
+		restore := tx.Model(reflectNew(tx.node)).Unscoped().
+			Where(tx.Statement.Quote(tx.deletedAt.DBName)+" = ?", deletedAt).
+			Scopes(tx.scoped(stored))
+		if tx.softDeleteMode == SoftDeleteKeepSlots {
+			restore = restore.Where(tx.replacePlaceholder("[tree_id] = ? AND [left] >= ? AND [left] <= ?"),
+				tx.getTreeID(stored), tx.getLeft(stored), tx.getRight(stored))
+		} else {
+			restore = restore.Where(tx.colTree()+" = ?", tx.getTreeID(stored))
+		}
+		if err = restore.Update(tx.deletedAt.DBName, nil).Error; err != nil {
+			return err
+		}
+
+		if tx.softDeleteMode == SoftDeleteArchive {
+			// 归档树先作为新的树恢复，再移动到目标位置
+			treeID, err := tx.getNextTreeId(stored)
+			if err != nil {
+				return err
+			}
+			err = tx.Model(reflectNew(tx.node)).Where(tx.colTree()+" = ?", tx.getTreeID(stored)).
+				Scopes(tx.scoped(stored)).
+				Update(tx.colTree(true), treeID).Error
+			if err != nil {
+				return err
+			}
+			tx.setTreeID(stored, treeID)
+			tx.setParentID(stored, tx.rootParentID())
+			err = tx.Model(reflectNew(tx.node)).Select(tx.colParent()).
+				Where(tx.colID()+" = ?", tx.getNodeID(stored)).Updates(stored).Error
+			if err != nil {
+				return err
+			}
+		} else if targetPtr == nil {
+			// 仍在原来的位置
+			return tx.RefreshNode(n)
+		}
+		if target != nil {
+			if err = tx.moveNode(stored, target, position); err != nil {
+				return err
+			}
+		}
+		return tx.RefreshNode(n)
+	})
+}
+
+// liveNodes 去掉nodes（按lft排序的一棵子树）中被软删除的节点及其子孙节点，
+// 例如db为Unscoped时查询到的被软删除的行
+func (t *tree) liveNodes(nodes []interface{}) []interface{} {
+	if !t.softDeleted() {
+		return nodes
+	}
+	var (
+		result       []interface{}
+		deletedRight int
+	)
+	for _, node := range nodes {
+		if t.getLeft(node) < deletedRight {
+			continue
+		}
+		if deletedAt, _ := getFieldValue(t.context(), node, t.deletedAt).(gorm.DeletedAt); deletedAt.Valid {
+			deletedRight = t.getRight(node)
+			continue
+		}
+		result = append(result, node)
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateSubtree(t *testing.T) {
+	assert.Nil(t, cleanTable(new(Category)))
+	defer cleanTable(new(Category))
+	assert.Nil(t, cleanTable(new(Product)))
+	defer cleanTable(new(Product))
+	manager, err := mptt.NewTreeManager(globalDb, new(Category))
+	assert.Nil(t, err)
+	var (
+		electronics = &Category{Name: "Electronics"}
+		phones      = &Category{Name: "Phones"}
+		android     = &Category{Name: "Android"}
+		laptops     = &Category{Name: "Laptops"}
+		books       = &Category{Name: "Books"}
+	)
+	assert.Nil(t, manager.CreateNode(electronics))
+	assert.Nil(t, manager.CreateNode(books))
+	assert.Nil(t, manager.InsertNode(phones, electronics, mptt.LastChild))
+	assert.Nil(t, manager.InsertNode(laptops, electronics, mptt.LastChild))
+	assert.Nil(t, manager.InsertNode(android, phones, mptt.LastChild))
+	for category, prices := range map[*Category][]int{electronics: {5}, phones: {10, 20}, android: {1, 2, 3}, laptops: {100}} {
+		for _, price := range prices {
+			assert.Nil(t, globalDb.Create(&Product{CategoryID: category.ID, Name: category.Name, Price: price}).Error)
+		}
+	}
+	type result struct {
+		Name  string
+		Value int
+	}
+	counts := func(list []*Category, sum bool) []result {
+		results := make([]result, 0, len(list))
+		for _, item := range list {
+			if sum {
+				results = append(results, result{item.Name, item.PriceTotal})
+			} else {
+				results = append(results, result{item.Name, item.ProductCount})
+			}
+		}
+		return results
+	}
+
+	var list []*Category
+	assert.Nil(t, manager.AnnotateCumulativeCount(&list, new(Product), "category_id", "ProductCount"))
+	assert.Equal(t, []result{{"Electronics", 7}, {"Phones", 5}, {"Android", 3}, {"Laptops", 1}, {"Books", 0}}, counts(list, false))
+
+	list = nil
+	assert.Nil(t, manager.AnnotateCumulativeCount(&list, new(Product), "category_id", "ProductCount", mptt.WithDirectOnly()))
+	assert.Equal(t, []result{{"Electronics", 1}, {"Phones", 2}, {"Android", 3}, {"Laptops", 1}, {"Books", 0}}, counts(list, false))
+
+	list = nil
+	assert.Nil(t, manager.AggregateSubtree(&list, new(Product), "category_id", "SUM(product.price)", "PriceTotal",
+		mptt.WithAggregateRoot(phones)))
+	assert.Equal(t, []result{{"Phones", 36}, {"Android", 6}}, counts(list, true))
+	assert.Equal(t, 2, list[0].Lvl)
+
+	typed, err := mptt.NewTypedTreeManager[Category](globalDb)
+	assert.Nil(t, err)
+	list, err = typed.AggregateSubtree(context.Background(), new(Product), "category_id", "MAX(product.price)", "PriceTotal",
+		mptt.WithDirectOnly())
+	assert.Nil(t, err)
+	assert.Equal(t, []result{{"Electronics", 5}, {"Phones", 20}, {"Android", 3}, {"Laptops", 100}, {"Books", 0}}, counts(list, true))
+
+	assert.ErrorIs(t, manager.AnnotateCumulativeCount(&list, new(Product), "category_id", "Unknown"), mptt.UnknownFieldError)
+	assert.ErrorIs(t, manager.AnnotateCumulativeCount(&list, nil, "category_id", "ProductCount"), mptt.ModelTypeError)
+}
+
+func TestAggregateSubtreeSoftDelete(t *testing.T) {
+	assert.Nil(t, cleanTable(new(SoftCategory)))
+	defer cleanTable(new(SoftCategory))
+	assert.Nil(t, cleanTable(new(Product)))
+	defer cleanTable(new(Product))
+	manager, err := mptt.NewTreeManager(globalDb, new(SoftCategory))
+	assert.Nil(t, err)
+	root, a, b := &SoftCategory{Name: "root"}, &SoftCategory{Name: "a"}, &SoftCategory{Name: "b"}
+	assert.Nil(t, manager.CreateNode(root))
+	assert.Nil(t, manager.InsertNode(a, root, mptt.LastChild))
+	assert.Nil(t, manager.InsertNode(b, root, mptt.LastChild))
+	for category, count := range map[*SoftCategory]int{root: 1, a: 2, b: 3} {
+		for i := 0; i < count; i++ {
+			assert.Nil(t, globalDb.Create(&Product{CategoryID: category.ID, Name: category.Name}).Error)
+		}
+	}
+	assert.Nil(t, manager.DeleteNode(b))
+
+	// the deleted node is neither loaded nor counted
+	var list []*SoftCategory
+	assert.Nil(t, manager.AnnotateCumulativeCount(&list, new(Product), "category_id", "ProductCount"))
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "root", list[0].Name)
+		assert.Equal(t, 3, list[0].ProductCount)
+		assert.Equal(t, "a", list[1].Name)
+		assert.Equal(t, 2, list[1].ProductCount)
+	}
+}
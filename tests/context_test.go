@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestCancelledContext(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = manager.WithContext(ctx).CreateNode(&CustomTree{Name: "cancelled"})
+	assert.ErrorIs(t, err, context.Canceled)
+	err = manager.WithContext(ctx).Rebuild()
+	assert.ErrorIs(t, err, context.Canceled)
+	var count int64
+	assert.Nil(t, globalDb.Model(new(CustomTree)).Count(&count).Error)
+	assert.EqualValues(t, 0, count)
+}
+
+func TestCancelRebuild(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	for _, node := range rawNodes {
+		err = dfs(node, func(n *Node) (int, error) {
+			return rawCreate(manager, n)
+		})
+		assert.Nil(t, err)
+	}
+
+	// cancel the context after the third update statement of the rebuild
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := 0
+	err = globalDb.Callback().Update().After("gorm:update").Register("tests:cancel", func(db *gorm.DB) {
+		if db.Statement.Context == ctx {
+			if updates++; updates == 3 {
+				cancel()
+			}
+		}
+	})
+	assert.Nil(t, err)
+	defer globalDb.Callback().Update().Remove("tests:cancel")
+
+	err = manager.WithContext(ctx).Rebuild()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 3, updates)
+
+	assert.Nil(t, manager.WithContext(context.Background()).Rebuild())
+	item, err := getItemByName(manager, "dev department")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, item.Lft)
+	assert.EqualValues(t, 30, item.Rght)
+}
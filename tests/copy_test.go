@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func descendantNames(t *testing.T, manager mptt.TreeManager, node *CustomTree) []string {
+	assert.Nil(t, manager.RefreshNode(node))
+	var descendants []*CustomTree
+	assert.Nil(t, manager.Node(node).GetDescendants(&descendants, false))
+	names := make([]string, 0, len(descendants))
+	for _, descendant := range descendants {
+		names = append(names, descendant.Name)
+	}
+	return names
+}
+
+func TestCopySubtree(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	for _, node := range rawNodes {
+		assert.Nil(t, dfs(node, func(n *Node) (int, error) {
+			return createNode(manager, n)
+		}))
+	}
+	validate := func() {
+		report, err := manager.Validate()
+		assert.Nil(t, err)
+		assert.True(t, report.Valid(), "%+v", report)
+	}
+	nodes, err := getAllNodes(manager)
+	assert.Nil(t, err)
+	designCenter := nodes["design center"]
+	designNames := descendantNames(t, manager, designCenter)
+
+	// across trees, the clone func adjusts the names
+	copied, err := manager.CopySubtree(nodes["dev group 1"], designCenter, mptt.FirstChild,
+		mptt.WithCloneFunc(func(source, clone interface{}) error {
+			clone.(*CustomTree).Name = source.(*CustomTree).Name + " (copy)"
+			return nil
+		}))
+	assert.Nil(t, err)
+	validate()
+	root := copied.(*CustomTree)
+	assert.NotEqual(t, nodes["dev group 1"].ID, root.ID)
+	assert.EqualValues(t, designCenter.ID, root.ParentID)
+	assert.EqualValues(t, 3, root.Lvl)
+	assert.Equal(t, append([]string{"dev group 1 (copy)", "dev team 1 (copy)", "dev team 2 (copy)"}, designNames...),
+		descendantNames(t, manager, designCenter))
+	assert.Equal(t, []string{"dev team 1", "dev team 2"}, descendantNames(t, manager, nodes["dev group 1"]))
+
+	// within the tree, the copy of a subtree into itself
+	copied, err = manager.CopySubtree(nodes["dev center"], nodes["dev team 4"], mptt.LastChild)
+	assert.Nil(t, err)
+	validate()
+	assert.EqualValues(t, 5, copied.(*CustomTree).Lvl)
+	assert.Equal(t, []string{"dev group 1", "dev team 1", "dev team 2", "dev group 2", "dev team 3", "dev team 4",
+		"dev center", "dev group 1", "dev team 1", "dev team 2", "dev group 2", "dev team 3", "dev team 4"},
+		descendantNames(t, manager, nodes["dev center"]))
+
+	// as new trees
+	copied, err = manager.CopySubtree(nodes["test group 2"], nil, "")
+	assert.Nil(t, err)
+	validate()
+	assert.EqualValues(t, 3, copied.(*CustomTree).TreeID)
+	assert.EqualValues(t, 1, copied.(*CustomTree).Lvl)
+	assert.EqualValues(t, 0, copied.(*CustomTree).ParentID)
+	copied, err = manager.CopySubtree(nodes["product department"], nodes["dev department"], mptt.Right,
+		mptt.WithCopyBatchSize(2))
+	assert.Nil(t, err)
+	validate()
+	assert.EqualValues(t, 2, copied.(*CustomTree).TreeID)
+	assert.Len(t, descendantNames(t, manager, copied.(*CustomTree)), 17)
+	assert.Nil(t, manager.RefreshNode(nodes["product department"]))
+	assert.EqualValues(t, 3, nodes["product department"].TreeID)
+}
+
+func TestCopySubtreeSoftDeleted(t *testing.T) {
+	assert.Nil(t, cleanTable(new(SoftTree)))
+	defer cleanTable(new(SoftTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(SoftTree))
+	assert.Nil(t, err)
+	nodes := createSoftTrees(t, manager)
+	assert.Nil(t, manager.DeleteNode(nodes["b"]))
+	created := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Nil(t, globalDb.Unscoped().Model(new(SoftTree)).Where("1 = 1").
+		UpdateColumns(map[string]interface{}{"created_at": created, "updated_at": created}).Error)
+
+	// the soft-deleted rows loaded by an unscoped db are not copied
+	unscoped, err := mptt.NewTreeManager(globalDb.Unscoped(), new(SoftTree))
+	assert.Nil(t, err)
+	copied, err := unscoped.CopySubtree(nodes["a"], nil, "")
+	assert.Nil(t, err)
+	assertSoftTreeValid(t, manager)
+	root := copied.(*SoftTree)
+	assert.Equal(t, []string{"c"}, softDescendantNames(t, manager, root))
+	assert.False(t, root.DeletedAt.Valid)
+	assert.True(t, root.CreatedAt.After(created))
+	assert.True(t, root.UpdatedAt.After(created))
+	var count int64
+	assert.Nil(t, globalDb.Unscoped().Model(new(SoftTree)).Where("name = ?", "b1").Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+
+	_, err = unscoped.CopySubtree(getSoftNode(t, manager, "b"), nil, "")
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+}
+
+func TestCopySubtreeUUID(t *testing.T) {
+	assert.Nil(t, cleanTable(new(UUIDTree)))
+	defer cleanTable(new(UUIDTree))
+	ctx := context.Background()
+	manager, err := mptt.NewTypedTreeManager[UUIDTree](globalDb)
+	assert.Nil(t, err)
+	root := &UUIDTree{ID: uuid.New(), Name: "root"}
+	assert.Nil(t, manager.Create(ctx, root))
+	child := &UUIDTree{ID: uuid.New(), ParentID: root.ID, Name: "child"}
+	assert.Nil(t, manager.Create(ctx, child))
+
+	// ids not generated by the database are set by the clone func
+	copied, err := manager.Copy(ctx, root, root, mptt.LastChild, mptt.WithCloneFunc(func(_, clone interface{}) error {
+		clone.(*UUIDTree).ID = uuid.New()
+		return nil
+	}))
+	assert.Nil(t, err)
+	children, err := manager.Children(ctx, copied)
+	assert.Nil(t, err)
+	assert.Len(t, children, 1)
+	assert.Equal(t, "child", children[0].Name)
+	report, err := manager.Manager().Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid(), "%+v", report)
+}
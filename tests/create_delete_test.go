@@ -142,3 +142,62 @@ func TestBFSCreate(t *testing.T) {
 	assert.EqualValues(t, item.Lft, 23)
 	assert.EqualValues(t, item.Rght, 28)
 }
+
+func TestDeleteNodeKeepChildren(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	for _, node := range rawNodes {
+		assert.Nil(t, dfs(node, func(n *Node) (int, error) {
+			return createNode(manager, n)
+		}))
+	}
+	validate := func() {
+		report, err := manager.Validate()
+		assert.Nil(t, err)
+		assert.True(t, report.Valid(), "%+v", report)
+	}
+
+	// the groups take the place of the dev center
+	devCenter, err := getItemByName(manager, "dev center")
+	assert.Nil(t, err)
+	assert.Nil(t, manager.DeleteNodeKeepChildren(devCenter))
+	validate()
+	nodes, err := getAllNodes(manager)
+	assert.Nil(t, err)
+	assert.NotContains(t, nodes, "dev center")
+	for name, left := range map[string]int{"dev group 1": 2, "dev group 2": 8, "test center": 14} {
+		assert.EqualValues(t, nodes["dev department"].ID, nodes[name].ParentID, name)
+		assert.EqualValues(t, 2, nodes[name].Lvl, name)
+		assert.EqualValues(t, left, nodes[name].Lft, name)
+	}
+	assert.EqualValues(t, 3, nodes["dev team 1"].Lvl)
+	assert.EqualValues(t, 28, nodes["dev department"].Rght)
+
+	// each child of a deleted root becomes a tree, the following trees are shifted
+	assert.Nil(t, manager.DeleteNodeKeepChildren(nodes["dev department"]))
+	validate()
+	nodes, err = getAllNodes(manager)
+	assert.Nil(t, err)
+	for name, treeID := range map[string]int{"dev group 1": 1, "dev group 2": 2, "test center": 3, "product department": 4} {
+		assert.EqualValues(t, 0, nodes[name].ParentID, name)
+		assert.EqualValues(t, treeID, nodes[name].TreeID, name)
+		assert.EqualValues(t, 1, nodes[name].Lft, name)
+		assert.EqualValues(t, 1, nodes[name].Lvl, name)
+	}
+	assert.EqualValues(t, 14, nodes["test center"].Rght)
+	assert.EqualValues(t, 2, nodes["test group 1"].Lvl)
+	assert.EqualValues(t, 3, nodes["test team 1"].Lvl)
+
+	// a leaf root closes the tree id gap
+	team := &CustomTree{Name: "single"}
+	assert.Nil(t, manager.InsertNode(team, nodes["dev group 1"], mptt.Left))
+	assert.EqualValues(t, 1, team.TreeID)
+	assert.Nil(t, manager.DeleteNodeKeepChildren(team))
+	validate()
+	nodes, err = getAllNodes(manager)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, nodes["dev group 1"].TreeID)
+	assert.EqualValues(t, 4, nodes["product department"].TreeID)
+}
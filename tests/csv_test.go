@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+)
+
+func countNodes(t *testing.T, manager mptt.TreeManager) int64 {
+	var count int64
+	assert.Nil(t, manager.GormDB().Model(new(CustomTree)).Count(&count).Error)
+	return count
+}
+
+func TestExportImportCSV(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	roots := make([]*CustomTree, 0, len(rawNodes))
+	for _, node := range rawNodes {
+		roots = append(roots, toCustomTree(node))
+	}
+	assert.Nil(t, manager.ImportTree(roots))
+
+	var adjacency, paths bytes.Buffer
+	assert.Nil(t, manager.ExportCSV(&adjacency, nil))
+	assert.Nil(t, manager.ExportCSV(&paths, nil, mptt.WithCSVFormat(mptt.CSVPath)))
+	lines := strings.Split(strings.TrimSpace(paths.String()), "\n")
+	assert.Equal(t, "path", lines[0])
+	assert.Equal(t, rawNodes[0].Name, lines[1])
+	assert.Equal(t, rawNodes[0].Name+"/"+rawNodes[0].Children[0].Name, lines[2])
+	lines = strings.Split(strings.TrimSpace(adjacency.String()), "\n")
+	assert.Equal(t, "id,parent_id,name", lines[0])
+	assert.Len(t, lines, int(countNodes(t, manager))+1)
+
+	// the adjacency rows recreate the same forest
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	report, err := manager.ImportCSV(bytes.NewReader(adjacency.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, len(lines)-1, report.Rows)
+	assert.Equal(t, len(rawNodes), report.Roots)
+	integrity, err := manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, integrity.Valid(), "%+v", integrity)
+	var again bytes.Buffer
+	assert.Nil(t, manager.ExportCSV(&again, nil, mptt.WithCSVFormat(mptt.CSVPath)))
+	assert.Equal(t, paths.String(), again.String())
+}
+
+func TestImportCSVPaths(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+
+	// children before their parents and missing ancestors
+	data := "path\nElectronics/Phones/Android\nElectronics\nElectronics/Phones/iOS\nBooks/Fiction\n"
+	report, err := manager.ImportCSV(strings.NewReader(data), mptt.WithCSVDryRun())
+	assert.Nil(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 4, report.Rows)
+	assert.Equal(t, 2, report.Roots)
+	assert.Equal(t, []string{"Electronics", "Electronics/Phones", "Electronics/Phones/Android",
+		"Electronics/Phones/iOS", "Books", "Books/Fiction"}, report.Inserts)
+	assert.Equal(t, []string{"Electronics/Phones", "Books"}, report.Implied)
+	assert.EqualValues(t, 0, countNodes(t, manager))
+
+	_, err = manager.ImportCSV(strings.NewReader(data))
+	assert.Nil(t, err)
+	android, err := getItemByName(manager, "Android")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, android.Lvl)
+	books, err := getItemByName(manager, "Books")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, books.TreeID)
+
+	// under an existing node, with another separator
+	_, err = manager.ImportCSV(strings.NewReader("path\nTablets > Android\n"),
+		mptt.WithCSVPathSeparator(">"), mptt.WithCSVTarget(books, mptt.Right))
+	assert.Nil(t, err)
+	tablets, err := getItemByName(manager, "Tablets")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, tablets.TreeID)
+	integrity, err := manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, integrity.Valid(), "%+v", integrity)
+}
+
+func TestImportCSVErrors(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	for _, data := range []string{
+		"path\nA/B\nA\nA/B\n",
+		"path\nA//B\n",
+		"id,parent_id,name\n1,,a\n1,,b\n",
+		"id,parent_id,name\n1,,a\n2,3,b\n",
+		"id,parent_id,name\n1,,a\n2,3,b\n3,2,c\n4,4,d\n",
+		"id,name\n1,a\n",
+	} {
+		_, err = manager.ImportCSV(strings.NewReader(data))
+		assert.ErrorIs(t, err, mptt.InvalidCSVError, data)
+	}
+	_, err = manager.ImportCSV(strings.NewReader("id,parent_id,name\n1,,a\n2,3,b\n3,2,c\n"))
+	assert.Contains(t, err.Error(), "cycle among ids 2,3")
+	assert.EqualValues(t, 0, countNodes(t, manager))
+}
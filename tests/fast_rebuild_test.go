@@ -0,0 +1,104 @@
+package tests
+
+import (
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFastRebuild(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	for _, node := range rawNodes {
+		err = bfs(node, func(n *Node) (int, error) {
+			return rawCreate(manager, n)
+		})
+		assert.Nil(t, err)
+	}
+	changed, err := manager.FastRebuild()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 30, changed)
+	nodeMap, err := getAllNodes(manager)
+	assert.Nil(t, err)
+	for _, testcase := range rebuildTestcase {
+		node, ok := nodeMap[testcase.name]
+		assert.True(t, ok)
+		assert.EqualValues(t, 1, node.TreeID)
+		assert.EqualValues(t, testcase.level, node.Lvl)
+		assert.EqualValues(t, testcase.left, node.Lft)
+		assert.EqualValues(t, testcase.right, node.Rght)
+	}
+	assert.EqualValues(t, 2, nodeMap["design group 2"].TreeID)
+
+	// nothing to change
+	changed, err = manager.FastRebuild()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, changed)
+
+	// only the broken row of the given tree is written
+	err = globalDb.Model(nodeMap["dev team 1"]).Update("lft", 5).Error
+	assert.Nil(t, err)
+	err = globalDb.Model(nodeMap["design team 1"]).Update("lft", 5).Error
+	assert.Nil(t, err)
+	changed, err = manager.FastRebuild(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, changed)
+	item, err := getItemByName(manager, "dev team 1")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 4, item.Lft)
+	item, err = getItemByName(manager, "design team 1")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 5, item.Lft)
+
+	// the same result as Rebuild
+	assert.Nil(t, manager.Rebuild())
+	changed, err = manager.FastRebuild()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, changed)
+}
+
+func TestFastRebuildStrayTreeID(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	for _, node := range rawNodes {
+		err = bfs(node, func(n *Node) (int, error) {
+			return rawCreate(manager, n)
+		})
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, manager.Rebuild())
+	nodeMap, err := getAllNodes(manager)
+	assert.Nil(t, err)
+
+	// a subtree of tree 1 whose tree_id is wrong is found through parent_id
+	for _, name := range []string{"dev group 1", "dev team 1", "dev team 2"} {
+		assert.Nil(t, globalDb.Model(nodeMap[name]).Update("tree_id", 2).Error)
+	}
+	changed, err := manager.FastRebuild(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, changed)
+	report, err := manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid(), "%+v", report)
+	for _, name := range []string{"dev group 1", "dev team 1", "dev team 2"} {
+		item, err := getItemByName(manager, name)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 1, item.TreeID)
+		assert.EqualValues(t, nodeMap[name].Lft, item.Lft)
+	}
+
+	// a subtree of tree 2 moved under tree 1 leaves a gap in tree 2, which is fixed too
+	assert.Nil(t, globalDb.Model(nodeMap["design group 2"]).Update("parent_id", nodeMap["dev center"].ID).Error)
+	_, err = manager.FastRebuild(1)
+	assert.Nil(t, err)
+	report, err = manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid(), "%+v", report)
+	item, err := getItemByName(manager, "design team 4")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, item.TreeID)
+}
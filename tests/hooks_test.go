@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedEvent struct {
+	hookType    mptt.HookType
+	name        string
+	oldParentID interface{}
+	newParentID interface{}
+	oldTreeID   int
+	newTreeID   int
+	left        int
+	right       int
+}
+
+// recordHooks register a hook for every hook type, appending the events to events
+func recordHooks(events *[]recordedEvent) []mptt.Option {
+	var opts []mptt.Option
+	for _, hookType := range []mptt.HookType{
+		mptt.BeforeTreeInsertHook, mptt.AfterTreeInsertHook,
+		mptt.BeforeTreeMoveHook, mptt.AfterTreeMoveHook,
+		mptt.BeforeTreeDeleteHook, mptt.AfterTreeDeleteHook,
+	} {
+		hookType := hookType
+		opts = append(opts, mptt.WithHook(hookType, func(event *mptt.TreeEvent) error {
+			*events = append(*events, recordedEvent{
+				hookType:    hookType,
+				name:        event.Node.(*HookedTree).Name,
+				oldParentID: event.OldParentID,
+				newParentID: event.NewParentID,
+				oldTreeID:   event.OldTreeID,
+				newTreeID:   event.NewTreeID,
+				left:        event.Left,
+				right:       event.Right,
+			})
+			return nil
+		}))
+	}
+	return opts
+}
+
+func TestTreeHooks(t *testing.T) {
+	assert.Nil(t, cleanTable(new(HookedTree)))
+	defer cleanTable(new(HookedTree))
+	var events []recordedEvent
+	manager, err := mptt.NewTreeManager(globalDb, new(HookedTree), recordHooks(&events)...)
+	assert.Nil(t, err)
+
+	rootA := &HookedTree{Name: "a"}
+	assert.Nil(t, manager.CreateNode(rootA))
+	rootB := &HookedTree{Name: "b"}
+	assert.Nil(t, manager.CreateNode(rootB))
+	child := &HookedTree{Name: "c"}
+	child.ParentID = rootA.ID
+	assert.Nil(t, manager.CreateNode(child))
+	assert.Equal(t, []recordedEvent{
+		{hookType: mptt.BeforeTreeInsertHook, name: "a", newParentID: 0},
+		{hookType: mptt.AfterTreeInsertHook, name: "a", newParentID: 0, newTreeID: 1, left: 1, right: 2},
+		{hookType: mptt.BeforeTreeInsertHook, name: "b", newParentID: 0},
+		{hookType: mptt.AfterTreeInsertHook, name: "b", newParentID: 0, newTreeID: 2, left: 1, right: 2},
+		{hookType: mptt.BeforeTreeInsertHook, name: "c", newParentID: rootA.ID},
+		{hookType: mptt.AfterTreeInsertHook, name: "c", newParentID: rootA.ID, newTreeID: 1, left: 2, right: 3},
+	}, events)
+
+	// the after hook sees the stored position of the moved node
+	events = nil
+	grandChild := &HookedTree{Name: "d"}
+	assert.Nil(t, manager.InsertNode(grandChild, child, mptt.LastChild))
+	assert.Nil(t, manager.RefreshNode(child))
+	assert.Nil(t, manager.RefreshNode(rootB))
+	_, err = manager.MoveNode(child, rootB, mptt.FirstChild)
+	assert.Nil(t, err)
+	assert.Equal(t, []recordedEvent{
+		{hookType: mptt.BeforeTreeInsertHook, name: "d", newParentID: child.ID},
+		{hookType: mptt.AfterTreeInsertHook, name: "d", newParentID: child.ID, newTreeID: 1, left: 3, right: 4},
+		{hookType: mptt.BeforeTreeMoveHook, name: "c", oldParentID: rootA.ID, newParentID: rootB.ID,
+			oldTreeID: 1, left: 2, right: 5},
+		{hookType: mptt.AfterTreeMoveHook, name: "c", oldParentID: rootA.ID, newParentID: rootB.ID,
+			oldTreeID: 1, newTreeID: 2, left: 2, right: 5},
+	}, events)
+
+	// the deleted range is reported once for the whole subtree
+	events = nil
+	assert.Nil(t, manager.DeleteNode(child))
+	assert.Equal(t, []recordedEvent{
+		{hookType: mptt.BeforeTreeDeleteHook, name: "c", oldParentID: rootB.ID, oldTreeID: 2, left: 2, right: 5},
+		{hookType: mptt.AfterTreeDeleteHook, name: "c", oldParentID: rootB.ID, oldTreeID: 2, left: 2, right: 5},
+	}, events)
+	report, err := manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid(), "%+v", report)
+}
+
+func TestTreeHookErrors(t *testing.T) {
+	assert.Nil(t, cleanTable(new(HookedTree)))
+	defer cleanTable(new(HookedTree))
+	denied := errors.New("denied")
+	manager, err := mptt.NewTreeManager(globalDb, new(HookedTree),
+		mptt.WithHook(mptt.AfterTreeMoveHook, func(event *mptt.TreeEvent) error {
+			if event.NewTreeID != event.OldTreeID {
+				return denied
+			}
+			return nil
+		}),
+		mptt.WithHook(mptt.AfterTreeDeleteHook, func(event *mptt.TreeEvent) error {
+			// changes made with the transaction are rolled back together with the delete
+			if err := event.Tx.Create(&HookedTree{Name: "audit"}).Error; err != nil {
+				return err
+			}
+			return denied
+		}))
+	assert.Nil(t, err)
+
+	// the hook method of the model
+	assert.NotNil(t, manager.CreateNode(&HookedTree{}))
+
+	root := &HookedTree{Name: "root"}
+	assert.Nil(t, manager.CreateNode(root))
+	other := &HookedTree{Name: "other"}
+	assert.Nil(t, manager.CreateNode(other))
+	child := &HookedTree{Name: "child"}
+	assert.Nil(t, manager.InsertNode(child, root, mptt.LastChild))
+
+	// the move is rolled back when the after hook fails
+	_, err = manager.MoveNode(child, other, mptt.LastChild)
+	assert.Equal(t, denied, err)
+	assert.Nil(t, manager.RefreshNode(child))
+	assert.EqualValues(t, root.ID, child.ParentID)
+	assert.EqualValues(t, root.TreeID, child.TreeID)
+
+	assert.Equal(t, denied, manager.DeleteNode(child))
+	var count int64
+	assert.Nil(t, globalDb.Model(new(HookedTree)).Count(&count).Error)
+	assert.EqualValues(t, 3, count)
+	report, err := manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid(), "%+v", report)
+}
+
+func TestBulkInsertHooks(t *testing.T) {
+	assert.Nil(t, cleanTable(new(HookedTree)))
+	defer cleanTable(new(HookedTree))
+	var events []recordedEvent
+	manager, err := mptt.NewTreeManager(globalDb, new(HookedTree), recordHooks(&events)...)
+	assert.Nil(t, err)
+	children := make(map[interface{}][]interface{})
+	childrenOf := mptt.WithImportChildren(func(node interface{}) []interface{} {
+		return children[node]
+	})
+
+	root, child := &HookedTree{Name: "x"}, &HookedTree{Name: "y"}
+	children[root] = []interface{}{child}
+	assert.Nil(t, manager.ImportTree([]*HookedTree{root}, childrenOf))
+	assert.Equal(t, []recordedEvent{
+		{hookType: mptt.BeforeTreeInsertHook, name: "x", newParentID: 0},
+		{hookType: mptt.BeforeTreeInsertHook, name: "y", newParentID: 0},
+		{hookType: mptt.AfterTreeInsertHook, name: "x", newParentID: 0, newTreeID: 1, left: 1, right: 4},
+		{hookType: mptt.AfterTreeInsertHook, name: "y", newParentID: root.ID, newTreeID: 1, left: 2, right: 3},
+	}, events)
+
+	// every clone of a copy
+	events = nil
+	copied, err := manager.CopySubtree(root, root, mptt.LastChild)
+	assert.Nil(t, err)
+	assert.Equal(t, []recordedEvent{
+		{hookType: mptt.BeforeTreeInsertHook, name: "x", newParentID: root.ID},
+		{hookType: mptt.BeforeTreeInsertHook, name: "y", newParentID: 0},
+		{hookType: mptt.AfterTreeInsertHook, name: "x", newParentID: root.ID, newTreeID: 1, left: 4, right: 7},
+		{hookType: mptt.AfterTreeInsertHook, name: "y", newParentID: copied.(*HookedTree).ID, newTreeID: 1, left: 5, right: 6},
+	}, events)
+
+	// the imports of json and csv go through ImportTree
+	events = nil
+	assert.Nil(t, manager.ImportJSON(strings.NewReader(`{"Name": "j"}`), nil, mptt.LastChild))
+	assert.Len(t, events, 2)
+
+	// the hook method of the model rolls back the whole import
+	invalid := &HookedTree{}
+	root = &HookedTree{Name: "z"}
+	children[root] = []interface{}{invalid}
+	assert.NotNil(t, manager.ImportTree([]*HookedTree{root}, childrenOf))
+	var count int64
+	assert.Nil(t, globalDb.Model(new(HookedTree)).Count(&count).Error)
+	assert.EqualValues(t, 5, count)
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+)
+
+func toCustomTree(node *Node) *CustomTree {
+	item := &CustomTree{Name: node.Name}
+	for _, child := range node.Children {
+		item.Children = append(item.Children, toCustomTree(child))
+	}
+	return item
+}
+
+func TestImportTree(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	validate := func() {
+		report, err := manager.Validate()
+		assert.Nil(t, err)
+		assert.True(t, report.Valid(), "%+v", report)
+	}
+
+	roots := make([]*CustomTree, 0, len(rawNodes))
+	for _, node := range rawNodes {
+		roots = append(roots, toCustomTree(node))
+	}
+	assert.Nil(t, manager.ImportTree(roots))
+	validate()
+	// the same result as CreateNode one by one
+	item, err := getItemByName(manager, "dev team 4")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 4, item.Lvl)
+	assert.EqualValues(t, 12, item.Lft)
+	assert.EqualValues(t, 13, item.Rght)
+	item, err = getItemByName(manager, "design group 2")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, item.TreeID)
+	assert.EqualValues(t, 3, item.Lvl)
+	assert.EqualValues(t, 23, item.Lft)
+	assert.EqualValues(t, 28, item.Rght)
+	assert.EqualValues(t, roots[1].Children[1].ID, item.ParentID)
+
+	// under an existing node
+	sub := toCustomTree(rawNodes[0].Children[1])
+	parent, err := getItemByName(manager, "dev center")
+	assert.Nil(t, err)
+	assert.Nil(t, manager.ImportTree([]*CustomTree{sub}, mptt.WithImportParent(parent), mptt.WithImportBatchSize(2)))
+	validate()
+	assert.EqualValues(t, parent.ID, sub.ParentID)
+	assert.EqualValues(t, 3, sub.Lvl)
+	assert.EqualValues(t, parent.Rght, sub.Lft)
+	assert.Len(t, descendantNames(t, manager, parent), 13)
+
+	// a larger tree, 1 + 10 + 100 + 1000 nodes
+	var build func(name string, depth int) *CustomTree
+	build = func(name string, depth int) *CustomTree {
+		node := &CustomTree{Name: name}
+		if depth < 3 {
+			for i := 0; i < 10; i++ {
+				node.Children = append(node.Children, build(fmt.Sprintf("%s-%d", name, i), depth+1))
+			}
+		}
+		return node
+	}
+	big := build("big", 0)
+	assert.Nil(t, manager.ImportTree([]*CustomTree{big}))
+	validate()
+	assert.EqualValues(t, 3, big.TreeID)
+	assert.EqualValues(t, 2222, big.Rght)
+}
+
+func TestImportTreeScoped(t *testing.T) {
+	assert.Nil(t, cleanTable(new(ScopedTree)))
+	defer cleanTable(new(ScopedTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(ScopedTree), mptt.WithScopeColumns("TenantID"))
+	assert.Nil(t, err)
+	existing := &ScopedTree{TenantID: 2, Name: "existing"}
+	assert.Nil(t, manager.CreateNode(existing))
+
+	// the children are given by an accessor and inherit the scope of their root
+	a, b := &ScopedTree{TenantID: 1, Name: "a"}, &ScopedTree{TenantID: 2, Name: "b"}
+	children := map[*ScopedTree][]interface{}{
+		a: {&ScopedTree{Name: "a1"}, &ScopedTree{Name: "a2"}},
+		b: {&ScopedTree{Name: "b1"}},
+	}
+	err = manager.ImportTree([]*ScopedTree{a, b}, mptt.WithImportChildren(func(node interface{}) []interface{} {
+		return children[node.(*ScopedTree)]
+	}))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, a.TreeID)
+	assert.EqualValues(t, 6, a.Rght)
+	assert.EqualValues(t, 2, b.TreeID)
+	b1 := children[b][0].(*ScopedTree)
+	assert.EqualValues(t, 2, b1.TenantID)
+	assert.EqualValues(t, b.ID, b1.ParentID)
+	report, err := manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid(), "%+v", report)
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+)
+
+// preorderNames the names of node and its descendants in preorder, with their depth
+func preorderNames(nodes []*Node, depth int, out []string) []string {
+	for _, node := range nodes {
+		out = append(out, fmt.Sprintf("%d:%s", depth, node.Name))
+		out = preorderNames(node.Children, depth+1, out)
+	}
+	return out
+}
+
+func TestExportJSON(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	roots := make([]*CustomTree, 0, len(rawNodes))
+	for _, node := range rawNodes {
+		roots = append(roots, toCustomTree(node))
+	}
+	assert.Nil(t, manager.ImportTree(roots))
+
+	// the whole forest has the shape of the Node fixtures
+	var buf bytes.Buffer
+	assert.Nil(t, manager.ExportJSON(&buf, nil, mptt.WithJSONFields("Name"), mptt.WithJSONIndent("  ")))
+	var forest []*Node
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &forest))
+	assert.Equal(t, preorderNames(rawNodes, 0, nil), preorderNames(forest, 0, nil))
+
+	// a subtree with all the non-tree columns
+	devCenter, err := getItemByName(manager, "dev center")
+	assert.Nil(t, err)
+	buf.Reset()
+	assert.Nil(t, manager.ExportJSON(&buf, devCenter))
+	var object map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &object))
+	assert.EqualValues(t, devCenter.ID, object["id"])
+	assert.Equal(t, "dev center", object["Name"])
+	for _, key := range []string{"parent_id", "tree_id", "lft", "rght", "lvl", "Children"} {
+		assert.NotContains(t, object, key)
+	}
+	assert.Len(t, object["children"], len(rawNodes[0].Children[1].Children))
+}
+
+func TestImportJSON(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	validate := func() {
+		report, err := manager.Validate()
+		assert.Nil(t, err)
+		assert.True(t, report.Valid(), "%+v", report)
+	}
+	roots := make([]*CustomTree, 0, len(rawNodes))
+	for _, node := range rawNodes {
+		roots = append(roots, toCustomTree(node))
+	}
+	assert.Nil(t, manager.ImportTree(roots))
+	var forest bytes.Buffer
+	assert.Nil(t, manager.ExportJSON(&forest, nil))
+	before, err := getAllNodes(manager)
+	assert.Nil(t, err)
+
+	// a copy of a subtree with new ids, as the first child of another node
+	devCenter, err := getItemByName(manager, "dev center")
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	assert.Nil(t, manager.ExportJSON(&buf, devCenter))
+	designCenter, err := getItemByName(manager, "design center")
+	assert.Nil(t, err)
+	assert.Nil(t, manager.ImportJSON(&buf, designCenter, mptt.FirstChild))
+	validate()
+	var children []*CustomTree
+	assert.Nil(t, manager.Node(designCenter).GetChildren(&children))
+	assert.Equal(t, "dev center", children[0].Name)
+	assert.NotEqual(t, devCenter.ID, children[0].ID)
+	assert.Equal(t, descendantNames(t, manager, devCenter), descendantNames(t, manager, children[0]))
+
+	// the forest as new trees left of the first tree
+	first, err := getItemByName(manager, rawNodes[0].Name)
+	assert.Nil(t, err)
+	assert.Nil(t, manager.ImportJSON(bytes.NewReader(forest.Bytes()), first, mptt.Left))
+	validate()
+	assert.Nil(t, manager.RefreshNode(first))
+	assert.EqualValues(t, len(rawNodes)+1, first.TreeID)
+
+	// restore the snapshot with the original ids
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	assert.Nil(t, manager.ImportJSON(bytes.NewReader(forest.Bytes()), nil, mptt.LastChild, mptt.WithJSONKeepIDs()))
+	validate()
+	after, err := getAllNodes(manager)
+	assert.Nil(t, err)
+	assert.Equal(t, len(before), len(after))
+	for name, node := range before {
+		restored := after[name]
+		if assert.NotNil(t, restored, name) {
+			assert.Equal(t, node.ModelBase, restored.ModelBase, name)
+		}
+	}
+
+	// a malformed document is rejected before anything is written
+	err = manager.ImportJSON(bytes.NewReader([]byte(`[{"Name": "x", "children": 1}]`)), nil, mptt.LastChild)
+	assert.ErrorIs(t, err, mptt.ModelTypeError)
+}
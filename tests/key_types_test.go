@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"context"
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// testKeyTypes runs create, query, move, rebuild and delete on a model with a custom id type
+func testKeyTypes[T any](t *testing.T, newNode func(name string, parent *T) *T, nameOf func(*T) string) {
+	model := new(T)
+	assert.Nil(t, cleanTable(model))
+	defer cleanTable(model)
+	ctx := context.Background()
+	manager, err := mptt.NewTypedTreeManager[T](globalDb)
+	assert.Nil(t, err)
+	names := func(nodes []*T, err error) []string {
+		assert.Nil(t, err)
+		list := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			list = append(list, nameOf(node))
+		}
+		return list
+	}
+	validate := func() {
+		report, err := manager.Manager().Validate()
+		assert.Nil(t, err)
+		assert.True(t, report.Valid(), "%+v", report)
+	}
+
+	root := newNode("root", nil)
+	assert.Nil(t, manager.Create(ctx, root))
+	a := newNode("a", root)
+	assert.Nil(t, manager.Create(ctx, a))
+	b := newNode("b", root)
+	assert.Nil(t, manager.Create(ctx, b))
+	for _, name := range []string{"a1", "a2"} {
+		assert.Nil(t, manager.Create(ctx, newNode(name, a)))
+	}
+	second := newNode("second", nil)
+	assert.Nil(t, manager.Create(ctx, second))
+	validate()
+
+	for _, node := range []*T{root, a, b} {
+		assert.Nil(t, manager.Refresh(ctx, node))
+	}
+	assert.Equal(t, []string{"a", "b"}, names(manager.Children(ctx, root)))
+	assert.Equal(t, []string{"a", "a1", "a2", "b"}, names(manager.Descendants(ctx, root, false)))
+	children, err := manager.Children(ctx, a)
+	assert.Nil(t, err)
+	a2 := children[1]
+	assert.Equal(t, []string{"root", "a"}, names(manager.Ancestors(ctx, a2, true, false)))
+	found, err := manager.Root(ctx, a2)
+	assert.Nil(t, err)
+	assert.Equal(t, "root", nameOf(found))
+
+	assert.Nil(t, manager.Move(ctx, a2, b, mptt.FirstChild))
+	assert.Nil(t, manager.Refresh(ctx, a))
+	assert.Nil(t, manager.Move(ctx, a, second, mptt.LastChild))
+	assert.Nil(t, manager.Refresh(ctx, root))
+	assert.Nil(t, manager.Refresh(ctx, second))
+	assert.Equal(t, []string{"b", "a2"}, names(manager.Descendants(ctx, root, false)))
+	assert.Equal(t, []string{"a", "a1"}, names(manager.Descendants(ctx, second, false)))
+	validate()
+
+	breakNumbers := func() {
+		err := globalDb.Session(&gorm.Session{NewDB: true, AllowGlobalUpdate: true}).Model(model).
+			Updates(map[string]interface{}{"lft": 0, "rght": 0, "lvl": 0}).Error
+		assert.Nil(t, err)
+	}
+	breakNumbers()
+	assert.Nil(t, manager.Rebuild(ctx))
+	validate()
+	breakNumbers()
+	assert.Nil(t, manager.Manager().PartialRebuild(1))
+	assert.Nil(t, manager.Manager().PartialRebuild(2))
+	validate()
+	breakNumbers()
+	changed, err := manager.Manager().FastRebuild()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 6, changed)
+	validate()
+
+	assert.Nil(t, manager.Refresh(ctx, a))
+	assert.Nil(t, manager.Delete(ctx, a))
+	assert.Nil(t, manager.Refresh(ctx, second))
+	assert.Empty(t, names(manager.Descendants(ctx, second, false)))
+	validate()
+}
+
+func TestUUIDKeys(t *testing.T) {
+	testKeyTypes(t, func(name string, parent *UUIDTree) *UUIDTree {
+		node := &UUIDTree{ID: uuid.New(), Name: name}
+		if parent != nil {
+			node.ParentID = parent.ID
+		}
+		return node
+	}, func(node *UUIDTree) string {
+		return node.Name
+	})
+}
+
+func TestBinaryKeys(t *testing.T) {
+	var next byte
+	testKeyTypes(t, func(name string, parent *BinaryTree) *BinaryTree {
+		next++
+		node := &BinaryTree{ID: BinaryID{15: next}, Name: name}
+		if parent != nil {
+			node.ParentID = parent.ID
+		}
+		return node
+	}, func(node *BinaryTree) string {
+		return node.Name
+	})
+}
+
+func TestBinaryKeysValidate(t *testing.T) {
+	assert.Nil(t, cleanTable(new(BinaryTree)))
+	defer cleanTable(new(BinaryTree))
+	ctx := context.Background()
+	manager, err := mptt.NewTypedTreeManager[BinaryTree](globalDb)
+	assert.Nil(t, err)
+	root := &BinaryTree{ID: BinaryID{15: 1}, Name: "root"}
+	assert.Nil(t, manager.Create(ctx, root))
+	a := &BinaryTree{ID: BinaryID{15: 2}, ParentID: root.ID, Name: "a"}
+	assert.Nil(t, manager.Create(ctx, a))
+	b := &BinaryTree{ID: BinaryID{15: 3}, ParentID: root.ID, Name: "b"}
+	assert.Nil(t, manager.Create(ctx, b))
+
+	assert.Nil(t, globalDb.Model(a).Update("lvl", 5).Error)
+	assert.Nil(t, globalDb.Model(b).Update("parent_id", BinaryID{15: 9}).Error)
+	report, err := manager.Manager().Validate()
+	assert.Nil(t, err)
+	assert.False(t, report.Valid())
+	assert.Equal(t, []interface{}{a.ID}, report.Violations[mptt.ViolationLevel])
+	assert.Equal(t, []interface{}{b.ID}, report.Violations[mptt.ViolationOrphan])
+	assert.Equal(t, []int{1}, report.TreeIDs)
+
+	assert.Nil(t, globalDb.Model(b).Update("parent_id", root.ID).Error)
+	assert.Nil(t, manager.Rebuild(ctx))
+	report, err = manager.Manager().Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid(), "%+v", report)
+}
+
+func TestStringKeys(t *testing.T) {
+	testKeyTypes(t, func(name string, parent *StringTree) *StringTree {
+		node := &StringTree{ID: "node-" + name, Name: name}
+		if parent != nil {
+			node.ParentID = parent.ID
+		}
+		return node
+	}, func(node *StringTree) string {
+		return node.Name
+	})
+}
+
+func TestUint64Keys(t *testing.T) {
+	testKeyTypes(t, func(name string, parent *Uint64Tree) *Uint64Tree {
+		node := &Uint64Tree{Name: name}
+		if parent != nil {
+			node.ParentID = parent.ID
+		}
+		return node
+	}, func(node *Uint64Tree) string {
+		return node.Name
+	})
+}
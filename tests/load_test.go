@@ -0,0 +1,67 @@
+package tests
+
+import (
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+)
+
+func TestLoadNested(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	for _, node := range rawNodes {
+		err = dfs(node, func(n *Node) (int, error) {
+			return createNode(manager, n)
+		})
+		assert.Nil(t, err)
+	}
+	dept, err := getItemByName(manager, "dev department")
+	assert.Nil(t, err)
+
+	var root CustomTree
+	assert.Nil(t, manager.Node(dept).GetTree(&root))
+	assert.Equal(t, "dev department", root.Name)
+	assert.Equal(t, 2, len(root.Children))
+	assert.Equal(t, "test center", root.Children[1].Name)
+	assert.Equal(t, "test group 2", root.Children[1].Children[1].Name)
+	assert.Equal(t, "test team 4", root.Children[1].Children[1].Children[1].Name)
+	assert.Nil(t, root.Children[1].Children[1].Children[1].Children)
+
+	root = CustomTree{}
+	assert.Nil(t, manager.Node(dept).GetTree(&root, mptt.WithMaxDepth(2)))
+	assert.Equal(t, 2, len(root.Children))
+	assert.Nil(t, root.Children[0].Children)
+
+	root = CustomTree{}
+	err = manager.Node(dept).GetTree(&root, mptt.WithNodeFilter(func(node interface{}) bool {
+		return !strings.HasPrefix(node.(*CustomTree).Name, "test")
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(root.Children))
+	assert.Equal(t, "dev center", root.Children[0].Name)
+
+	// the start node itself is filtered out
+	root = CustomTree{}
+	err = manager.Node(dept).GetTree(&root, mptt.WithNodeFilter(func(node interface{}) bool {
+		return node.(*CustomTree).Name != dept.Name
+	}))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.Equal(t, 0, root.ID)
+
+	var roots []*CustomTree
+	assert.Nil(t, manager.LoadForest(&roots))
+	assert.Equal(t, 2, len(roots))
+	assert.Equal(t, "product department", roots[1].Name)
+	assert.Equal(t, "design team 4", roots[1].Children[1].Children[1].Children[1].Name)
+
+	assert.Nil(t, manager.LoadForest(&roots, mptt.WithMaxDepth(1)))
+	assert.Equal(t, 2, len(roots))
+	assert.Nil(t, roots[0].Children)
+
+	err = manager.LoadForest(&roots, mptt.WithChildrenField("Name"))
+	assert.ErrorIs(t, err, mptt.UnknownFieldError)
+}
@@ -1,6 +1,16 @@
 package tests
 
-import mptt "github.com/boycs007/gorm-mptt"
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
 
 type CustomTree struct {
 	mptt.ModelBase
@@ -8,8 +18,155 @@ type CustomTree struct {
 	Children []*CustomTree `gorm:"-"`
 }
 
+// UniqueTree rejects duplicated names, used to break operations halfway
+type UniqueTree struct {
+	mptt.ModelBase
+	Name string `gorm:"type:varchar(125);uniqueIndex:unique_tree_name"`
+}
+
+// ScopedTree one forest per tenant
+type ScopedTree struct {
+	mptt.ModelBase
+	TenantID int    `gorm:"index"`
+	Name     string `gorm:"type:varchar(125)"`
+}
+
+// OrderedTree keeps siblings ordered by name
+type OrderedTree struct {
+	mptt.ModelBase
+	Name string `gorm:"type:varchar(125)"`
+}
+
+// UUIDTree uuid primary key, ids are set before creating
+type UUIDTree struct {
+	ID       uuid.UUID `gorm:"type:varchar(36);primaryKey"`
+	ParentID uuid.UUID `gorm:"type:varchar(36);index"`
+	TreeID   int       `gorm:"index"`
+	Lvl      int
+	Lft      int    `gorm:"index"`
+	Rght     int    `gorm:"index"`
+	Name     string `gorm:"type:varchar(125)"`
+}
+
+// BinaryID a plain [16]byte id stored as a blob
+type BinaryID [16]byte
+
+func (id BinaryID) Value() (driver.Value, error) {
+	return id[:], nil
+}
+
+func (id *BinaryID) Scan(value interface{}) error {
+	data, ok := value.([]byte)
+	if !ok || len(data) != len(id) {
+		return fmt.Errorf("invalid BinaryID: %v", value)
+	}
+	copy(id[:], data)
+	return nil
+}
+
+// BinaryTree [16]byte primary key, ids are set before creating
+type BinaryTree struct {
+	ID       BinaryID `gorm:"type:binary(16);primaryKey"`
+	ParentID BinaryID `gorm:"type:binary(16);index"`
+	TreeID   int      `gorm:"index"`
+	Lvl      int
+	Lft      int    `gorm:"index"`
+	Rght     int    `gorm:"index"`
+	Name     string `gorm:"type:varchar(125)"`
+}
+
+// StringTree string primary key
+type StringTree struct {
+	ID       string `gorm:"type:varchar(64);primaryKey"`
+	ParentID string `gorm:"type:varchar(64);index"`
+	TreeID   int    `gorm:"index"`
+	Lvl      int
+	Lft      int    `gorm:"index"`
+	Rght     int    `gorm:"index"`
+	Name     string `gorm:"type:varchar(125)"`
+}
+
+// Uint64Tree auto increment uint64 primary key
+type Uint64Tree struct {
+	ID       uint64 `gorm:"primaryKey"`
+	ParentID uint64 `gorm:"index"`
+	TreeID   int    `gorm:"index"`
+	Lvl      int
+	Lft      int    `gorm:"index"`
+	Rght     int    `gorm:"index"`
+	Name     string `gorm:"type:varchar(125)"`
+}
+
+// PtrParentTree roots are stored with a NULL parent_id
+type PtrParentTree struct {
+	ID       int  `gorm:"primaryKey"`
+	ParentID *int `gorm:"index"`
+	TreeID   int  `gorm:"index"`
+	Lvl      int
+	Lft      int    `gorm:"index"`
+	Rght     int    `gorm:"index"`
+	Name     string `gorm:"type:varchar(125)"`
+}
+
+// NullParentTree roots are stored with a NULL parent_id
+type NullParentTree struct {
+	ID       int           `gorm:"primaryKey"`
+	ParentID sql.NullInt64 `gorm:"index"`
+	TreeID   int           `gorm:"index"`
+	Lvl      int
+	Lft      int    `gorm:"index"`
+	Rght     int    `gorm:"index"`
+	Name     string `gorm:"type:varchar(125)"`
+}
+
 type Node struct {
 	Name     string  `json:"name"`
 	ParentID int     `json:"-"`
 	Children []*Node `json:"children,omitempty"`
 }
+
+// HookedTree rejects nodes without name before they are inserted
+type HookedTree struct {
+	mptt.ModelBase
+	Name string `gorm:"type:varchar(125)"`
+}
+
+func (n *HookedTree) BeforeTreeInsert(event *mptt.TreeEvent) error {
+	if n.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// SoftTree soft-deleted with gorm.DeletedAt
+type SoftTree struct {
+	mptt.ModelBase
+	Name      string `gorm:"type:varchar(125)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// Product belongs to a CustomTree category
+type Product struct {
+	ID         int `gorm:"primaryKey"`
+	CategoryID int `gorm:"index"`
+	Name       string
+	Price      int
+}
+
+// Category with the read-only aggregate fields filled by AnnotateCumulativeCount and AggregateSubtree
+type Category struct {
+	mptt.ModelBase
+	Name         string `gorm:"type:varchar(125)"`
+	ProductCount int    `gorm:"->;-:migration"`
+	PriceTotal   int    `gorm:"->;-:migration"`
+}
+
+// SoftCategory soft-deleted Category
+type SoftCategory struct {
+	mptt.ModelBase
+	Name         string         `gorm:"type:varchar(125)"`
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	ProductCount int            `gorm:"->;-:migration"`
+}
@@ -12,6 +12,7 @@ import (
  * @Date 2024/2/01 21:01
  **/
 func Test_Move(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
 	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
 	assert.Nil(t, err)
 	var f = func(node *Node) (int, error) {
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// testNullRoots checks roots are written as NULL and found with IS NULL
+func testNullRoots[T any](t *testing.T, newNode func(name string, parent *T) *T) {
+	model := new(T)
+	assert.Nil(t, cleanTable(model))
+	defer cleanTable(model)
+	ctx := context.Background()
+	manager, err := mptt.NewTypedTreeManager[T](globalDb)
+	assert.Nil(t, err)
+	countNull := func() int64 {
+		var count int64
+		assert.Nil(t, globalDb.Model(model).Where("parent_id IS NULL").Count(&count).Error)
+		return count
+	}
+
+	root := newNode("root", nil)
+	assert.Nil(t, manager.Create(ctx, root))
+	child := newNode("child", root)
+	assert.Nil(t, manager.Create(ctx, child))
+	assert.EqualValues(t, 1, countNull())
+	assert.True(t, manager.Manager().Node(root).IsRootNode())
+	assert.False(t, manager.Manager().Node(child).IsRootNode())
+
+	var roots []*T
+	assert.Nil(t, manager.Manager().RootNodes(&roots))
+	assert.Len(t, roots, 1)
+
+	// makeChildRootNode
+	assert.Nil(t, manager.Move(ctx, child, nil, mptt.LastChild))
+	assert.EqualValues(t, 2, countNull())
+	assert.True(t, manager.Manager().Node(child).IsRootNode())
+	var second T
+	assert.Nil(t, manager.Manager().RootNode(2, &second))
+	assert.Equal(t, child, &second)
+
+	assert.Nil(t, manager.Refresh(ctx, root))
+	assert.Nil(t, manager.Move(ctx, child, root, mptt.FirstChild))
+	assert.EqualValues(t, 1, countNull())
+	changed, err := manager.Manager().FastRebuild()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, changed)
+}
+
+func TestPtrParent(t *testing.T) {
+	newNode := func(name string, parent *PtrParentTree) *PtrParentTree {
+		node := &PtrParentTree{Name: name}
+		if parent != nil {
+			parentID := parent.ID
+			node.ParentID = &parentID
+		}
+		return node
+	}
+	testNullRoots(t, newNode)
+	testKeyTypes(t, newNode, func(node *PtrParentTree) string {
+		return node.Name
+	})
+}
+
+func TestNullParent(t *testing.T) {
+	newNode := func(name string, parent *NullParentTree) *NullParentTree {
+		node := &NullParentTree{Name: name}
+		if parent != nil {
+			node.ParentID = sql.NullInt64{Int64: int64(parent.ID), Valid: true}
+		}
+		return node
+	}
+	testNullRoots(t, newNode)
+	testKeyTypes(t, newNode, func(node *NullParentTree) string {
+		return node.Name
+	})
+}
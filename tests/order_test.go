@@ -0,0 +1,84 @@
+package tests
+
+import (
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func createOrderedNode(t *testing.T, manager mptt.TreeManager, parent *OrderedTree, name string) *OrderedTree {
+	node := &OrderedTree{Name: name}
+	if parent != nil {
+		node.ParentID = parent.ID
+	}
+	assert.Nil(t, manager.CreateNode(node))
+	return node
+}
+
+func orderedDescendantNames(t *testing.T, manager mptt.TreeManager, node *OrderedTree) []string {
+	assert.Nil(t, manager.RefreshNode(node))
+	var nodes []*OrderedTree
+	assert.Nil(t, manager.Node(node).GetDescendants(&nodes, false))
+	names := make([]string, 0, len(nodes))
+	for _, item := range nodes {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+func TestOrderInsertionBy(t *testing.T) {
+	assert.Nil(t, cleanTable(new(OrderedTree)))
+	manager, err := mptt.NewTreeManager(globalDb, new(OrderedTree), mptt.WithOrderInsertionBy("Name"))
+	assert.Nil(t, err)
+
+	rootB := createOrderedNode(t, manager, nil, "b")
+	rootC := createOrderedNode(t, manager, nil, "c")
+	rootA := createOrderedNode(t, manager, nil, "a")
+	for name, root := range map[string]*OrderedTree{"a": rootA, "b": rootB, "c": rootC} {
+		assert.Nil(t, manager.RefreshNode(root))
+		assert.Equal(t, int(name[0]-'a')+1, root.TreeID)
+	}
+
+	for _, name := range []string{"z", "x", "o", "y"} {
+		createOrderedNode(t, manager, rootA, name)
+	}
+	assert.Equal(t, []string{"o", "x", "y", "z"}, orderedDescendantNames(t, manager, rootA))
+
+	createOrderedNode(t, manager, rootB, "p")
+	createOrderedNode(t, manager, rootB, "m")
+	node, err := getOrderedNode(manager, "o")
+	assert.Nil(t, err)
+	assert.Nil(t, manager.RefreshNode(rootB))
+	ok, err := manager.MoveNode(node, rootB, mptt.LastChild)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"m", "o", "p"}, orderedDescendantNames(t, manager, rootB))
+	assert.Equal(t, []string{"x", "y", "z"}, orderedDescendantNames(t, manager, rootA))
+}
+
+func getOrderedNode(manager mptt.TreeManager, name string) (*OrderedTree, error) {
+	var ret OrderedTree
+	err := manager.GormDB().Where("name = ?", name).First(&ret).Error
+	return &ret, err
+}
+
+func TestReorderChildren(t *testing.T) {
+	assert.Nil(t, cleanTable(new(OrderedTree)))
+	manager, err := mptt.NewTreeManager(globalDb, new(OrderedTree))
+	assert.Nil(t, err)
+	root := createOrderedNode(t, manager, nil, "root")
+	three := createOrderedNode(t, manager, root, "3")
+	createOrderedNode(t, manager, three, "b")
+	createOrderedNode(t, manager, three, "a")
+	createOrderedNode(t, manager, root, "1")
+	createOrderedNode(t, manager, root, "2")
+	assert.Equal(t, []string{"3", "b", "a", "1", "2"}, orderedDescendantNames(t, manager, root))
+	assert.ErrorIs(t, manager.ReorderChildren(root), mptt.OrderInsertionByNotSetError)
+
+	orderedManager, err := mptt.NewTreeManager(globalDb, new(OrderedTree), mptt.WithOrderInsertionBy("Name"))
+	assert.Nil(t, err)
+	assert.Nil(t, orderedManager.ReorderChildren(root))
+	assert.Equal(t, []string{"1", "2", "3", "a", "b"}, orderedDescendantNames(t, manager, root))
+	assert.Equal(t, 1, root.Lft)
+	assert.Equal(t, 12, root.Rght)
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"errors"
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// breakParents makes dev team 2 an orphan and dev group 2 <-> dev team 3 a parent_id cycle
+func breakParents(t *testing.T, nodeMap map[string]*CustomTree) {
+	err := globalDb.Model(new(CustomTree)).Where("id = ?", nodeMap["dev team 2"].ID).
+		Update("parent_id", 99999).Error
+	assert.Nil(t, err)
+	err = globalDb.Model(new(CustomTree)).Where("id = ?", nodeMap["dev group 2"].ID).
+		Update("parent_id", nodeMap["dev team 3"].ID).Error
+	assert.Nil(t, err)
+}
+
+func TestBrokenParents(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	for _, node := range rawNodes {
+		err = bfs(node, func(n *Node) (int, error) {
+			return rawCreate(manager, n)
+		})
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, manager.Rebuild())
+	nodeMap, err := getAllNodes(manager)
+	assert.Nil(t, err)
+	breakParents(t, nodeMap)
+
+	for _, rebuild := range []func() error{
+		manager.Rebuild,
+		func() error { return manager.PartialRebuild(1) },
+		func() error {
+			_, err := manager.FastRebuild()
+			return err
+		},
+	} {
+		var broken *mptt.BrokenParentError
+		assert.True(t, errors.As(rebuild(), &broken))
+		assert.EqualValues(t, []interface{}{nodeMap["dev team 2"].ID}, broken.Orphans)
+		assert.EqualValues(t, [][]interface{}{{nodeMap["dev group 2"].ID, nodeMap["dev team 3"].ID}}, broken.Cycles)
+	}
+
+	// the orphan and the smallest id of the cycle become new roots
+	promoteManager, err := mptt.NewTreeManager(globalDb, new(CustomTree), mptt.WithPromoteOrphans())
+	assert.Nil(t, err)
+	assert.Nil(t, promoteManager.Rebuild())
+	report, err := promoteManager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid())
+	team2, err := getItemByName(manager, "dev team 2")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, team2.ParentID)
+	assert.EqualValues(t, 1, team2.Lvl)
+	group2, err := getItemByName(manager, "dev group 2")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, group2.ParentID)
+	assert.EqualValues(t, 6, group2.Rght)
+	assert.NotEqual(t, team2.TreeID, group2.TreeID)
+
+	// restore and attach under the lost and found node, which is in another tree
+	assert.Nil(t, globalDb.Model(new(CustomTree)).Where("id = ?", group2.ID).
+		Update("parent_id", nodeMap["dev center"].ID).Error)
+	assert.Nil(t, globalDb.Model(new(CustomTree)).Where("id = ?", team2.ID).
+		Update("parent_id", nodeMap["dev group 1"].ID).Error)
+	assert.Nil(t, manager.Rebuild())
+	report, err = manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid())
+	nodeMap, err = getAllNodes(manager)
+	assert.Nil(t, err)
+	breakParents(t, nodeMap)
+	lostAndFound := nodeMap["design group 2"]
+	assert.NotEqual(t, 1, lostAndFound.TreeID)
+	lostManager, err := mptt.NewTreeManager(globalDb, new(CustomTree), mptt.WithLostAndFound(lostAndFound.ID))
+	assert.Nil(t, err)
+	_, err = lostManager.FastRebuild(1)
+	assert.Nil(t, err)
+	report, err = lostManager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid())
+	for _, name := range []string{"dev team 2", "dev group 2"} {
+		item, err := getItemByName(manager, name)
+		assert.Nil(t, err)
+		assert.EqualValues(t, lostAndFound.ID, item.ParentID)
+		assert.EqualValues(t, lostAndFound.TreeID, item.TreeID)
+	}
+	item, err := getItemByName(manager, "dev team 4")
+	assert.Nil(t, err)
+	assert.EqualValues(t, lostAndFound.TreeID, item.TreeID)
+
+	// the lost and found node must exist
+	breakParents(t, nodeMap)
+	missingManager, err := mptt.NewTreeManager(globalDb, new(CustomTree), mptt.WithLostAndFound(99999))
+	assert.Nil(t, err)
+	assert.NotNil(t, missingManager.Rebuild())
+}
+
+func TestBrokenParentsAcrossTrees(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	for _, node := range rawNodes {
+		err = bfs(node, func(n *Node) (int, error) {
+			return rawCreate(manager, n)
+		})
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, manager.Rebuild())
+	nodeMap, err := getAllNodes(manager)
+	assert.Nil(t, err)
+
+	// dev group 2 -> design team 3 -> design group 2 -> dev team 3 -> dev group 2, through trees 1 and 2
+	assert.Nil(t, globalDb.Model(new(CustomTree)).Where("id = ?", nodeMap["dev group 2"].ID).
+		Update("parent_id", nodeMap["design team 3"].ID).Error)
+	assert.Nil(t, globalDb.Model(new(CustomTree)).Where("id = ?", nodeMap["design group 2"].ID).
+		Update("parent_id", nodeMap["dev team 3"].ID).Error)
+	var broken *mptt.BrokenParentError
+	_, err = manager.FastRebuild(1)
+	assert.True(t, errors.As(err, &broken))
+	if assert.Len(t, broken.Cycles, 1) {
+		assert.ElementsMatch(t, []interface{}{nodeMap["dev group 2"].ID, nodeMap["design team 3"].ID,
+			nodeMap["design group 2"].ID, nodeMap["dev team 3"].ID}, broken.Cycles[0])
+	}
+	assert.True(t, errors.As(manager.PartialRebuild(2), &broken))
+
+	// the promoted node of the cycle is in tree 2, which is rebuilt too
+	promoteManager, err := mptt.NewTreeManager(globalDb, new(CustomTree), mptt.WithPromoteOrphans())
+	assert.Nil(t, err)
+	_, err = promoteManager.FastRebuild(1)
+	assert.Nil(t, err)
+	report, err := promoteManager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid(), "%+v", report)
+}
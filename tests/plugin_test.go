@@ -0,0 +1,237 @@
+package tests
+
+import (
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"testing"
+)
+
+// pluginDb shares the connection of globalDb, the callbacks are only registered on it
+func pluginDb(t *testing.T, plugin *mptt.Plugin) *gorm.DB {
+	sqlDB, err := globalDb.DB()
+	assert.Nil(t, err)
+	db, err := gorm.Open(sqlite.Dialector{DriverName: sqlite.DriverName, Conn: sqlDB},
+		&gorm.Config{NamingStrategy: globalDb.NamingStrategy, Logger: globalDb.Logger})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Use(plugin))
+	return db
+}
+
+func TestPlugin(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	db := pluginDb(t, mptt.NewPlugin())
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	validate := func() {
+		report, err := manager.Validate()
+		assert.Nil(t, err)
+		assert.True(t, report.Valid(), "%+v", report)
+	}
+
+	for _, node := range rawNodes {
+		err = bfs(node, func(n *Node) (int, error) {
+			item := &CustomTree{ModelBase: mptt.ModelBase{ParentID: n.ParentID}, Name: n.Name}
+			err := db.Create(item).Error
+			return item.ID, err
+		})
+		assert.Nil(t, err)
+	}
+	validate()
+	nodeMap, err := getAllNodes(manager)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, nodeMap["dev department"].Lft)
+	assert.EqualValues(t, 4, nodeMap["dev team 1"].Lvl)
+
+	// Save moves the node to its new parent
+	team1 := nodeMap["dev team 1"]
+	team1.ParentID = nodeMap["test group 2"].ID
+	assert.Nil(t, db.Save(team1).Error)
+	validate()
+	assert.Nil(t, manager.RefreshNode(nodeMap["test group 2"]))
+	assert.EqualValues(t, nodeMap["test group 2"].Rght-2, team1.Lft)
+
+	// stale mptt values in memory are not written back
+	team2 := nodeMap["dev team 2"]
+	team2.Name = "dev team 2 renamed"
+	assert.Nil(t, db.Save(team2).Error)
+	validate()
+	item, err := getItemByName(manager, "dev team 2 renamed")
+	assert.Nil(t, err)
+	assert.EqualValues(t, team2.Lft, item.Lft)
+	assert.Nil(t, db.Model(team2).Update("lft", 100).Error)
+	validate()
+
+	// Update of the parent column becomes a root, then back
+	assert.Nil(t, db.Model(team2).Update("parent_id", 0).Error)
+	validate()
+	assert.EqualValues(t, 1, team2.Lvl)
+	assert.Nil(t, db.Model(team2).Updates(map[string]interface{}{
+		"parent_id": nodeMap["dev group 2"].ID,
+		"name":      "dev team 2",
+	}).Error)
+	validate()
+	assert.EqualValues(t, 4, team2.Lvl)
+	err = db.Model(new(CustomTree)).Where("name = ?", "dev team 3").Update("parent_id", 1).Error
+	assert.ErrorIs(t, err, mptt.BulkParentUpdateError)
+
+	// Delete removes the descendants and closes the gap
+	assert.Nil(t, db.Delete(nodeMap["dev group 2"]).Error)
+	validate()
+	_, err = getItemByName(manager, "dev team 4")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.Nil(t, db.Where("name LIKE ?", "test team%").Delete(new(CustomTree)).Error)
+	validate()
+	_, err = getItemByName(manager, "test team 1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, db.Delete(new(CustomTree)).Error, gorm.ErrMissingWhereClause)
+}
+
+func TestPluginRegister(t *testing.T) {
+	assert.Nil(t, cleanTable(new(Uint64Tree)))
+	defer cleanTable(new(Uint64Tree))
+	db := pluginDb(t, mptt.NewPlugin().Register(new(Uint64Tree)))
+
+	roots := []*Uint64Tree{{Name: "root 1"}, {Name: "root 2"}}
+	assert.Nil(t, db.Create(&roots).Error)
+	child := &Uint64Tree{Name: "child", ParentID: roots[1].ID}
+	assert.Nil(t, db.Create(child).Error)
+	assert.EqualValues(t, 2, child.TreeID)
+	assert.EqualValues(t, 2, child.Lft)
+	assert.Nil(t, db.First(roots[1], roots[1].ID).Error)
+	assert.EqualValues(t, 4, roots[1].Rght)
+
+	// models which are not registered are untouched
+	assert.Nil(t, cleanTable(new(StringTree)))
+	defer cleanTable(new(StringTree))
+	plain := &StringTree{ID: "plain", Name: "plain"}
+	assert.Nil(t, db.Create(plain).Error)
+	assert.EqualValues(t, 0, plain.Lft)
+}
+
+func TestPluginManagerOnPluginDb(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	// the statements of the manager itself must not run through the plugin again
+	manager, err := mptt.NewTreeManager(pluginDb(t, mptt.NewPlugin()), new(CustomTree))
+	assert.Nil(t, err)
+	validate := func() {
+		report, err := manager.Validate()
+		assert.Nil(t, err)
+		assert.True(t, report.Valid(), "%+v", report)
+	}
+
+	root := &CustomTree{Name: "root"}
+	assert.Nil(t, manager.CreateNode(root))
+	a, b, c := &CustomTree{Name: "a"}, &CustomTree{Name: "b"}, &CustomTree{Name: "c"}
+	assert.Nil(t, manager.InsertNode(a, root, mptt.LastChild))
+	assert.Nil(t, manager.InsertNode(b, root, mptt.LastChild))
+	assert.Nil(t, manager.InsertNode(c, a, mptt.LastChild))
+	validate()
+	assert.Nil(t, manager.RefreshNode(root))
+	assert.EqualValues(t, 1, root.Lft)
+	assert.EqualValues(t, 8, root.Rght)
+	assert.EqualValues(t, 4, countNodes(t, manager))
+
+	assert.Nil(t, manager.RefreshNode(b))
+	_, err = manager.MoveNode(c, b, mptt.LastChild)
+	assert.Nil(t, err)
+	validate()
+	assert.Nil(t, manager.RefreshNode(b))
+	assert.EqualValues(t, 4, b.Lft)
+	assert.EqualValues(t, 7, b.Rght)
+
+	assert.Nil(t, manager.DeleteNode(b))
+	validate()
+	assert.Nil(t, manager.RefreshNode(root))
+	assert.EqualValues(t, 4, root.Rght)
+	assert.EqualValues(t, 2, countNodes(t, manager))
+}
+
+func TestPluginUpdateStruct(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	db := pluginDb(t, mptt.NewPlugin())
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	validate := func() {
+		report, err := manager.Validate()
+		assert.Nil(t, err)
+		assert.True(t, report.Valid(), "%+v", report)
+	}
+	root := &CustomTree{Name: "root"}
+	assert.Nil(t, db.Create(root).Error)
+	a := &CustomTree{Name: "a", ModelBase: mptt.ModelBase{ParentID: root.ID}}
+	assert.Nil(t, db.Create(a).Error)
+	b := &CustomTree{Name: "b", ModelBase: mptt.ModelBase{ParentID: root.ID}}
+	assert.Nil(t, db.Create(b).Error)
+	c := &CustomTree{Name: "c", ModelBase: mptt.ModelBase{ParentID: a.ID}}
+	assert.Nil(t, db.Create(c).Error)
+	validate()
+
+	// Updates with a struct moves the node to the parent of the struct, the mptt values are not written
+	err = db.Model(c).Updates(&CustomTree{Name: "c moved", ModelBase: mptt.ModelBase{ParentID: b.ID, Lft: 100}}).Error
+	assert.Nil(t, err)
+	validate()
+	item, err := getItemByName(manager, "c moved")
+	assert.Nil(t, err)
+	assert.Equal(t, b.ID, item.ParentID)
+	assert.Equal(t, item.Lft, c.Lft)
+	assert.Equal(t, b.ID, c.ParentID)
+
+	// Save after changing the parent, with stale mptt values in memory
+	c.ParentID = a.ID
+	c.Rght = 1
+	assert.Nil(t, db.Save(c).Error)
+	validate()
+	assert.Nil(t, manager.RefreshNode(a))
+	assert.Equal(t, a.Rght-2, c.Lft)
+
+	// bulk Updates with a struct: the parent needs a primary key, the mptt values are never written
+	err = db.Model(new(CustomTree)).Where("name = ?", "b").
+		Updates(&CustomTree{ModelBase: mptt.ModelBase{ParentID: a.ID}}).Error
+	assert.ErrorIs(t, err, mptt.BulkParentUpdateError)
+	err = db.Model(new(CustomTree)).Where("name IN ?", []string{"a", "b"}).
+		Updates(CustomTree{Name: "renamed", ModelBase: mptt.ModelBase{Lft: 50, Rght: 51, TreeID: 9}}).Error
+	assert.Nil(t, err)
+	validate()
+}
+
+func TestPluginCreateClauses(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	db := pluginDb(t, mptt.NewPlugin())
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	validate := func() {
+		report, err := manager.Validate()
+		assert.Nil(t, err)
+		assert.True(t, report.Valid(), "%+v", report)
+	}
+	root := &CustomTree{Name: "root"}
+	assert.Nil(t, db.Create(root).Error)
+
+	// Omit and Select of the statement are kept, the mptt columns are always written
+	omitted := &CustomTree{Name: "omitted", ModelBase: mptt.ModelBase{ParentID: root.ID}}
+	assert.Nil(t, db.Omit("name", "lft").Create(omitted).Error)
+	selected := &CustomTree{Name: "selected", ModelBase: mptt.ModelBase{ParentID: root.ID}}
+	assert.Nil(t, db.Select("id").Create(selected).Error)
+	validate()
+	var names []string
+	assert.Nil(t, globalDb.Model(new(CustomTree)).Order("lft").Pluck("COALESCE(name, '')", &names).Error)
+	assert.Equal(t, []string{"root", "", ""}, names)
+
+	// existing nodes are skipped with ON CONFLICT DO NOTHING, other conflicts are rejected
+	assert.Nil(t, db.Clauses(clause.OnConflict{DoNothing: true}).Create(root).Error)
+	validate()
+	assert.EqualValues(t, 3, countNodes(t, manager))
+	err = db.Clauses(clause.OnConflict{UpdateAll: true}).Create(root).Error
+	assert.ErrorIs(t, err, mptt.UnsupportedOnConflictError)
+	fresh := &CustomTree{Name: "fresh", ModelBase: mptt.ModelBase{ParentID: root.ID}}
+	assert.Nil(t, db.Clauses(clause.OnConflict{DoNothing: true}).Create(fresh).Error)
+	validate()
+	assert.EqualValues(t, 4, countNodes(t, manager))
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"testing"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTreeScopes(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	assert.Nil(t, cleanTable(new(Product)))
+	defer cleanTable(new(Product))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	roots := make([]*CustomTree, 0, len(rawNodes))
+	for _, node := range rawNodes {
+		roots = append(roots, toCustomTree(node))
+	}
+	assert.Nil(t, manager.ImportTree(roots))
+	nodes, err := getAllNodes(manager)
+	assert.Nil(t, err)
+	names := func(list []*CustomTree) []string {
+		result := make([]string, 0, len(list))
+		for _, item := range list {
+			result = append(result, item.Name)
+		}
+		return result
+	}
+	find := func(scopes ...func(*gorm.DB) *gorm.DB) []string {
+		var list []*CustomTree
+		assert.Nil(t, globalDb.Model(new(CustomTree)).Scopes(scopes...).Order("tree_id, lft").Find(&list).Error)
+		return names(list)
+	}
+
+	devCenter := nodes["dev center"]
+	assert.Equal(t, []string{"dev group 1", "dev group 2"}, find(manager.ChildrenOf(devCenter)))
+	assert.Equal(t, []string{"dev team 1", "dev team 2", "dev team 3", "dev team 4"}, find(manager.LeavesOf(devCenter)))
+	assert.Equal(t, []string{"dev department", "dev center", "dev group 1"},
+		find(manager.AncestorsOf(nodes["dev team 2"], false)))
+	assert.Equal(t, []string{"dev group 2"}, find(manager.SiblingsOf(nodes["dev group 1"], false)))
+	assert.Equal(t, find(manager.AtLevel(1)), find(manager.Roots()))
+	assert.Len(t, find(manager.Roots()), len(rawNodes))
+	assert.Equal(t, []string{"dev team 3", "dev team 4"}, find(manager.DescendantsOf(devCenter, false), manager.AtLevel(4),
+		func(db *gorm.DB) *gorm.DB { return db.Where("name > ?", "dev team 2") }))
+
+	// products whose category is under dev center, the tree table joined under an alias
+	for i, name := range []string{"dev team 1", "test team 1", "dev center", "dev team 4", "dev group 2"} {
+		assert.Nil(t, globalDb.Create(&Product{CategoryID: nodes[name].ID, Name: name, Price: i}).Error)
+	}
+	var products []*Product
+	err = globalDb.Model(new(Product)).
+		Joins("JOIN custom_tree c ON c.id = product.category_id").
+		Scopes(manager.TableAs("c").DescendantsOf(devCenter, true)).
+		Order("product.price DESC").Offset(1).Limit(2).
+		Find(&products).Error
+	assert.Nil(t, err)
+	if assert.Len(t, products, 2) {
+		assert.Equal(t, "dev team 4", products[0].Name)
+		assert.Equal(t, "dev center", products[1].Name)
+	}
+	var count int64
+	err = globalDb.Model(new(Product)).
+		Joins("JOIN custom_tree c ON c.id = product.category_id").
+		Scopes(manager.TableAs("c").LeavesOf(nil)).
+		Count(&count).Error
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, count)
+}
+
+func TestTreeScopesSoftDelete(t *testing.T) {
+	assert.Nil(t, cleanTable(new(SoftTree)))
+	defer cleanTable(new(SoftTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(SoftTree))
+	assert.Nil(t, err)
+	root := &SoftTree{Name: "root"}
+	assert.Nil(t, manager.CreateNode(root))
+	a, b := &SoftTree{Name: "a"}, &SoftTree{Name: "b"}
+	assert.Nil(t, manager.InsertNode(a, root, mptt.LastChild))
+	assert.Nil(t, manager.InsertNode(b, root, mptt.LastChild))
+	assert.Nil(t, manager.InsertNode(&SoftTree{Name: "b1"}, b, mptt.LastChild))
+	assert.Nil(t, manager.DeleteNode(a))
+	assert.Nil(t, manager.RefreshNode(root))
+
+	// gorm does not add deleted_at IS NULL for the aliased table
+	count := func(scope func(*gorm.DB) *gorm.DB) int64 {
+		var result int64
+		assert.Nil(t, globalDb.Table("soft_tree AS s").Scopes(scope).Count(&result).Error)
+		return result
+	}
+	scopes := manager.TableAs("s")
+	assert.EqualValues(t, 1, count(scopes.ChildrenOf(root)))
+	assert.EqualValues(t, 2, count(scopes.DescendantsOf(root, false)))
+	assert.EqualValues(t, 1, count(scopes.LeavesOf(root)))
+	// only the root is left
+	assert.Nil(t, manager.RefreshNode(b))
+	assert.Nil(t, manager.DeleteNode(b))
+	assert.EqualValues(t, 1, count(scopes.LeavesOf(nil)))
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintTree(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	roots := make([]*CustomTree, 0, len(rawNodes))
+	for _, node := range rawNodes {
+		roots = append(roots, toCustomTree(node))
+	}
+	assert.Nil(t, manager.ImportTree(roots))
+
+	devCenter, err := getItemByName(manager, "dev center")
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	assert.Nil(t, manager.PrintTree(&buf, devCenter, nil))
+	assert.Equal(t, `dev center
+├── dev group 1
+│   ├── dev team 1
+│   └── dev team 2
+└── dev group 2
+    ├── dev team 3
+    └── dev team 4
+`, buf.String())
+
+	buf.Reset()
+	group, err := getItemByName(manager, "dev group 2")
+	assert.Nil(t, err)
+	assert.Nil(t, manager.PrintTree(&buf, group, func(node interface{}) string {
+		return strings.ToUpper(node.(*CustomTree).Name)
+	}, mptt.WithRenderNumbers()))
+	assert.Equal(t, "DEV GROUP 2 (9, 14)\n├── DEV TEAM 3 (10, 11)\n└── DEV TEAM 4 (12, 13)\n", buf.String())
+
+	// the whole forest, each root starts a new block
+	buf.Reset()
+	assert.Nil(t, manager.PrintTree(&buf, nil, nil))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Equal(t, int(countNodes(t, manager)), len(lines))
+	assert.Equal(t, rawNodes[0].Name, lines[0])
+	assert.Equal(t, "├── dev center", lines[1])
+	assert.Equal(t, "└── "+rawNodes[0].Children[len(rawNodes[0].Children)-1].Name, lines[8])
+	assert.Equal(t, rawNodes[1].Name, lines[15])
+}
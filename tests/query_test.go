@@ -21,6 +21,7 @@ func caseBefore(t *testing.T) {
 	if queryManager != nil {
 		return
 	}
+	assert.Nil(t, cleanTable(new(CustomTree)))
 	var err error
 	queryManager, err = mptt.NewTreeManager(globalDb, new(CustomTree))
 	assert.Nil(t, err)
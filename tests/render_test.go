@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTree(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	b, c := &CustomTree{Name: "b"}, &CustomTree{Name: `c "quoted"`}
+	a := &CustomTree{Name: "a", Children: []*CustomTree{b, c}}
+	assert.Nil(t, manager.ImportTree([]*CustomTree{a, {Name: "d"}}))
+
+	var buf bytes.Buffer
+	assert.Nil(t, manager.RenderDOT(&buf, nil, mptt.WithRenderNumbers()))
+	assert.Equal(t, "digraph tree {\n\tnode [shape=box];\n"+
+		"\tn0 [label=\"a\\n1-6 lvl 1\"];\n"+
+		"\tn1 [label=\"b\\n2-3 lvl 2\"];\n"+
+		"\tn2 [label=\"c \\\"quoted\\\"\\n4-5 lvl 2\"];\n"+
+		"\tn3 [label=\"d\\n1-2 lvl 1\"];\n"+
+		"\tn0 -> n1;\n\tn0 -> n2;\n}\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, manager.RenderMermaid(&buf, a, mptt.WithRenderFields("ID", "Name")))
+	assert.Equal(t, fmt.Sprintf("graph TD\n"+
+		"\tn0[\"%d a\"]\n\tn1[\"%d b\"]\n\tn2[\"%d c #quot;quoted#quot;\"]\n"+
+		"\tn0 --> n1\n\tn0 --> n2\n", a.ID, b.ID, c.ID), buf.String())
+
+	// c points to b while its interval is still under a
+	assert.Nil(t, globalDb.Model(c).Update("parent_id", b.ID).Error)
+	buf.Reset()
+	assert.Nil(t, manager.RenderDOT(&buf, a, mptt.WithRenderIntegrity()))
+	assert.Contains(t, buf.String(), "\tn2 [label=\"c \\\"quoted\\\"\\nparent\", color=red, style=filled, fillcolor=\"#ffd6d6\"];\n")
+	assert.Contains(t, buf.String(), "\tn1 -> n2 [style=dashed, color=red, label=\"parent_id\"];\n")
+	buf.Reset()
+	assert.Nil(t, manager.RenderMermaid(&buf, a, mptt.WithRenderIntegrity()))
+	assert.Contains(t, buf.String(), "\tn0 --> n2\n\tn1 -.->|parent_id| n2\n")
+	assert.Contains(t, buf.String(), "\tclass n2 violation\n")
+}
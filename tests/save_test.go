@@ -0,0 +1,55 @@
+package tests
+
+import (
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSaveNode(t *testing.T) {
+	assert.Nil(t, cleanTable(new(OrderedTree)))
+	defer cleanTable(new(OrderedTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(OrderedTree), mptt.WithOrderInsertionBy("Name"))
+	assert.Nil(t, err)
+
+	// a new node is created
+	rootA := &OrderedTree{Name: "a"}
+	assert.Nil(t, manager.SaveNode(rootA))
+	assert.EqualValues(t, 1, rootA.TreeID)
+	rootB := createOrderedNode(t, manager, nil, "b")
+	for _, name := range []string{"x", "z"} {
+		createOrderedNode(t, manager, rootA, name)
+	}
+	m := createOrderedNode(t, manager, rootB, "m")
+	createOrderedNode(t, manager, m, "m1")
+
+	// the parent and the name change together, the subtree is moved between x and z
+	assert.Nil(t, manager.RefreshNode(m))
+	m.ParentID = rootA.ID
+	m.Name = "y"
+	assert.Nil(t, manager.SaveNode(m))
+	assert.Equal(t, []string{"x", "y", "m1", "z"}, orderedDescendantNames(t, manager, rootA))
+	assert.Empty(t, orderedDescendantNames(t, manager, rootB))
+	assert.EqualValues(t, 2, m.Lvl)
+	assert.EqualValues(t, rootA.TreeID, m.TreeID)
+
+	// stale mptt values are ignored when only other columns changed
+	m.Lft, m.Rght = 100, 200
+	m.Name = "y2"
+	assert.Nil(t, manager.SaveNode(m))
+	node, err := getOrderedNode(manager, "y2")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 4, node.Lft)
+	assert.EqualValues(t, 4, m.Lft)
+
+	// parent cleared, the subtree becomes a root tree ordered by name
+	m.ParentID = 0
+	m.Name = "aa"
+	assert.Nil(t, manager.SaveNode(m))
+	assert.Nil(t, manager.RefreshNode(rootB))
+	assert.EqualValues(t, 2, m.TreeID)
+	assert.EqualValues(t, 3, rootB.TreeID)
+	report, err := manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid(), "%+v", report)
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func getScopedTree(t *testing.T, manager mptt.TreeManager, tenantID int) map[string]*ScopedTree {
+	var rows []*ScopedTree
+	err := manager.GormDB().Model(new(ScopedTree)).Where("tenant_id = ?", tenantID).Find(&rows).Error
+	assert.Nil(t, err)
+	ret := make(map[string]*ScopedTree)
+	for _, row := range rows {
+		ret[row.Name] = row
+	}
+	return ret
+}
+
+func TestScopedTrees(t *testing.T) {
+	assert.Nil(t, cleanTable(new(ScopedTree)))
+	manager, err := mptt.NewTreeManager(globalDb, new(ScopedTree), mptt.WithScopeColumns("TenantID"))
+	assert.Nil(t, err)
+
+	for _, tenantID := range []int{1, 2} {
+		for i, name := range []string{"r1", "r2", "r3"} {
+			root := &ScopedTree{TenantID: tenantID, Name: name}
+			assert.Nil(t, manager.CreateNode(root))
+			assert.Equal(t, i+1, root.TreeID)
+			child := &ScopedTree{ModelBase: mptt.ModelBase{ParentID: root.ID}, Name: name + "-c"}
+			assert.Nil(t, manager.CreateNode(child))
+			assert.Equal(t, tenantID, child.TenantID)
+			assert.Equal(t, root.TreeID, child.TreeID)
+		}
+	}
+	tenant1 := getScopedTree(t, manager, 1)
+	tenant2 := getScopedTree(t, manager, 2)
+
+	// a new root on the left only renumbers the trees of the same tenant
+	err = manager.InsertNode(&ScopedTree{Name: "r0"}, tenant1["r1"], mptt.Left)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, getScopedTree(t, manager, 1)["r1"].TreeID)
+	assert.Equal(t, 1, getScopedTree(t, manager, 2)["r1"].TreeID)
+
+	// siblings of roots are limited to the tenant
+	var siblings []*ScopedTree
+	err = manager.Node(tenant2["r2"]).GetSiblings(&siblings, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(siblings))
+	var next ScopedTree
+	err = manager.Node(tenant2["r2"]).GetNextSibling(&next)
+	assert.Nil(t, err)
+	assert.Equal(t, tenant2["r3"].ID, next.ID)
+
+	// move across tenants is refused
+	_, err = manager.MoveNode(tenant1["r1-c"], tenant2["r1"], mptt.LastChild)
+	assert.ErrorIs(t, err, mptt.ScopeMismatchError)
+	err = manager.InsertNode(&ScopedTree{TenantID: 2, Name: "x"}, tenant1["r1"], mptt.LastChild)
+	assert.ErrorIs(t, err, mptt.ScopeMismatchError)
+
+	// move a child to the root level of its own tenant
+	tenant2 = getScopedTree(t, manager, 2)
+	ok, err := manager.MoveNode(tenant2["r2-c"], tenant2["r1"], mptt.Left)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	tenant2 = getScopedTree(t, manager, 2)
+	assert.Equal(t, 1, tenant2["r2-c"].TreeID)
+	assert.Equal(t, 2, tenant2["r1"].TreeID)
+	assert.Equal(t, 4, getScopedTree(t, manager, 1)["r3"].TreeID)
+
+	// deleting a root closes the tree id gap of the tenant only
+	assert.Nil(t, manager.DeleteNode(tenant2["r2-c"]))
+	assert.Equal(t, 1, getScopedTree(t, manager, 2)["r1"].TreeID)
+	assert.Equal(t, 4, getScopedTree(t, manager, 1)["r3"].TreeID)
+}
+
+func TestScopedRebuild(t *testing.T) {
+	assert.Nil(t, cleanTable(new(ScopedTree)))
+	manager, err := mptt.NewTreeManager(globalDb, new(ScopedTree), mptt.WithScopeColumns("TenantID"))
+	assert.Nil(t, err)
+	for _, tenantID := range []int{1, 2} {
+		for _, name := range []string{"r1", "r2"} {
+			root := &ScopedTree{TenantID: tenantID, Name: name}
+			assert.Nil(t, globalDb.Create(root).Error)
+			child := &ScopedTree{ModelBase: mptt.ModelBase{ParentID: root.ID}, TenantID: tenantID, Name: name + "-c"}
+			assert.Nil(t, globalDb.Create(child).Error)
+		}
+	}
+	assert.Nil(t, manager.Rebuild())
+	for _, tenantID := range []int{1, 2} {
+		nodes := getScopedTree(t, manager, tenantID)
+		for i, name := range []string{"r1", "r2"} {
+			assert.Equal(t, i+1, nodes[name].TreeID)
+			assert.Equal(t, 1, nodes[name].Lft)
+			assert.Equal(t, 4, nodes[name].Rght)
+			assert.Equal(t, i+1, nodes[name+"-c"].TreeID)
+			assert.Equal(t, 2, nodes[name+"-c"].Lvl)
+		}
+	}
+}
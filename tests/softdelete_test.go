@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+)
+
+// createSoftTrees a(b(b1 b2) c) d
+func createSoftTrees(t *testing.T, manager mptt.TreeManager) map[string]*SoftTree {
+	nodes := make(map[string]*SoftTree)
+	for _, item := range [][2]string{{"a", ""}, {"b", "a"}, {"b1", "b"}, {"b2", "b"}, {"c", "a"}, {"d", ""}} {
+		node := &SoftTree{Name: item[0]}
+		if parent := nodes[item[1]]; parent != nil {
+			node.ParentID = parent.ID
+		}
+		assert.Nil(t, manager.CreateNode(node))
+		nodes[item[0]] = node
+	}
+	return nodes
+}
+
+// getSoftNode load the node by name, including the soft-deleted ones
+func getSoftNode(t *testing.T, manager mptt.TreeManager, name string) *SoftTree {
+	var node SoftTree
+	assert.Nil(t, manager.GormDB().Unscoped().Where("name = ?", name).First(&node).Error)
+	return &node
+}
+
+func softDescendantNames(t *testing.T, manager mptt.TreeManager, node *SoftTree) []string {
+	assert.Nil(t, manager.RefreshNode(node))
+	var descendants []*SoftTree
+	assert.Nil(t, manager.Node(node).GetDescendants(&descendants, false))
+	names := make([]string, 0, len(descendants))
+	for _, descendant := range descendants {
+		names = append(names, descendant.Name)
+	}
+	return names
+}
+
+func assertSoftTreeValid(t *testing.T, manager mptt.TreeManager) {
+	report, err := manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid(), "%+v", report)
+}
+
+func TestSoftDeleteKeepSlots(t *testing.T) {
+	assert.Nil(t, cleanTable(new(SoftTree)))
+	defer cleanTable(new(SoftTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(SoftTree))
+	assert.Nil(t, err)
+	nodes := createSoftTrees(t, manager)
+
+	// the soft-deleted rows keep their slots, the queries exclude them
+	assert.Nil(t, manager.DeleteNode(nodes["b"]))
+	assertSoftTreeValid(t, manager)
+	assert.Equal(t, []string{"c"}, softDescendantNames(t, manager, nodes["a"]))
+	assert.EqualValues(t, 10, nodes["a"].Rght)
+	assert.Equal(t, 1, manager.Node(nodes["a"]).GetDescendantCount())
+	b1 := getSoftNode(t, manager, "b1")
+	assert.True(t, b1.DeletedAt.Valid)
+	assert.EqualValues(t, 3, b1.Lft)
+
+	// new nodes do not overlap the deleted ones
+	e := &SoftTree{Name: "e"}
+	assert.Nil(t, manager.InsertNode(e, nodes["a"], mptt.FirstChild))
+	assertSoftTreeValid(t, manager)
+	assert.Equal(t, []string{"e", "c"}, softDescendantNames(t, manager, nodes["a"]))
+
+	// a node whose children are all deleted is a leaf
+	assert.Nil(t, manager.DeleteNode(e))
+	assert.Nil(t, manager.DeleteNode(nodes["c"]))
+	assert.Nil(t, manager.RefreshNode(nodes["a"]))
+	assert.True(t, manager.Node(nodes["a"]).IsLeafNode())
+	// a failed count query is not taken as a leaf
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, manager.WithContext(ctx).Node(nodes["a"]).IsLeafNode())
+	var leaves []*SoftTree
+	assert.Nil(t, manager.Node(nodes["a"]).GetLeafNodes(&leaves))
+	assert.Empty(t, leaves)
+
+	// the deleted subtree comes back in place, c stays deleted
+	assert.Nil(t, manager.RestoreNode(nodes["b"], nil, ""))
+	assertSoftTreeValid(t, manager)
+	assert.Equal(t, []string{"b", "b1", "b2"}, softDescendantNames(t, manager, nodes["a"]))
+	assert.False(t, nodes["b"].DeletedAt.Valid)
+	assert.Equal(t, mptt.NodeNotDeletedError, manager.RestoreNode(nodes["b"], nil, ""))
+
+	// descendants deleted before the subtree stay deleted, a restore needs a live parent
+	assert.Nil(t, manager.DeleteNode(getSoftNode(t, manager, "b1")))
+	assert.Nil(t, manager.DeleteNode(nodes["b"]))
+	assert.Equal(t, mptt.DeletedParentError, manager.RestoreNode(getSoftNode(t, manager, "b1"), nil, ""))
+	assert.Nil(t, manager.RestoreNode(getSoftNode(t, manager, "b"), nil, ""))
+	assert.Equal(t, []string{"b", "b2"}, softDescendantNames(t, manager, nodes["a"]))
+	b1 = getSoftNode(t, manager, "b1")
+	assert.Nil(t, manager.RestoreNode(b1, nodes["d"], mptt.LastChild))
+	assertSoftTreeValid(t, manager)
+	assert.Equal(t, []string{"b1"}, softDescendantNames(t, manager, nodes["d"]))
+
+	// a deleted tree keeps its tree id
+	assert.Nil(t, manager.DeleteNode(nodes["d"]))
+	f := &SoftTree{Name: "f"}
+	assert.Nil(t, manager.CreateNode(f))
+	assert.EqualValues(t, 3, f.TreeID)
+
+	// the deleted node stays as a leaf after its former children
+	assert.Nil(t, manager.DeleteNodeKeepChildren(nodes["b"]))
+	assertSoftTreeValid(t, manager)
+	assert.Equal(t, []string{"b2"}, softDescendantNames(t, manager, nodes["a"]))
+	assert.EqualValues(t, nodes["a"].ID, getSoftNode(t, manager, "b2").ParentID)
+	// the deleted children e and c become trees too
+	assert.Nil(t, manager.DeleteNodeKeepChildren(nodes["a"]))
+	assertSoftTreeValid(t, manager)
+	assert.EqualValues(t, 2, getSoftNode(t, manager, "b2").TreeID)
+	assert.EqualValues(t, 5, getSoftNode(t, manager, "a").TreeID)
+	assert.EqualValues(t, 7, getSoftNode(t, manager, "f").TreeID)
+
+	assert.Nil(t, manager.Rebuild())
+	assertSoftTreeValid(t, manager)
+}
+
+func TestSoftDeleteArchive(t *testing.T) {
+	assert.Nil(t, cleanTable(new(SoftTree)))
+	defer cleanTable(new(SoftTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(SoftTree), mptt.WithSoftDelete(mptt.SoftDeleteArchive))
+	assert.Nil(t, err)
+	nodes := createSoftTrees(t, manager)
+
+	// the subtree is detached into an archive tree, the gap is closed
+	assert.Nil(t, manager.DeleteNode(nodes["b"]))
+	assertSoftTreeValid(t, manager)
+	assert.Nil(t, manager.RefreshNode(nodes["a"]))
+	assert.EqualValues(t, 4, nodes["a"].Rght)
+	b, b1 := getSoftNode(t, manager, "b"), getSoftNode(t, manager, "b1")
+	assert.EqualValues(t, -1, b.TreeID)
+	assert.EqualValues(t, 1, b.Lft)
+	assert.EqualValues(t, 2, b1.Lvl)
+	assert.EqualValues(t, nodes["a"].ID, b.ParentID)
+
+	assert.Nil(t, manager.DeleteNode(nodes["d"]))
+	assert.EqualValues(t, -2, getSoftNode(t, manager, "d").TreeID)
+	f := &SoftTree{Name: "f"}
+	assert.Nil(t, manager.CreateNode(f))
+	assert.EqualValues(t, 2, f.TreeID)
+
+	// restored under the old parent, or as a new tree
+	assert.Nil(t, manager.RestoreNode(b, nil, ""))
+	assertSoftTreeValid(t, manager)
+	assert.Equal(t, []string{"c", "b", "b1", "b2"}, softDescendantNames(t, manager, nodes["a"]))
+	d := getSoftNode(t, manager, "d")
+	assert.Nil(t, manager.RestoreNode(d, nil, ""))
+	assertSoftTreeValid(t, manager)
+	assert.EqualValues(t, 3, d.TreeID)
+	assert.False(t, d.DeletedAt.Valid)
+
+	assert.Nil(t, manager.DeleteNodeKeepChildren(nodes["a"]))
+	assertSoftTreeValid(t, manager)
+	assert.EqualValues(t, -1, getSoftNode(t, manager, "a").TreeID)
+	assert.EqualValues(t, 1, getSoftNode(t, manager, "c").TreeID)
+	assert.EqualValues(t, 2, getSoftNode(t, manager, "b").TreeID)
+	assert.EqualValues(t, 4, getSoftNode(t, manager, "d").TreeID)
+
+	assert.Nil(t, manager.Rebuild())
+	assertSoftTreeValid(t, manager)
+}
+
+func TestSoftDeleteDisabled(t *testing.T) {
+	assert.Nil(t, cleanTable(new(SoftTree)))
+	defer cleanTable(new(SoftTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(SoftTree), mptt.WithSoftDelete(mptt.SoftDeleteDisabled))
+	assert.Nil(t, err)
+	nodes := createSoftTrees(t, manager)
+	assert.Nil(t, manager.DeleteNode(nodes["b"]))
+	assertSoftTreeValid(t, manager)
+	var count int64
+	assert.Nil(t, globalDb.Unscoped().Model(new(SoftTree)).Count(&count).Error)
+	assert.EqualValues(t, 3, count)
+	assert.Equal(t, mptt.SoftDeleteNotEnabledError, manager.RestoreNode(nodes["b"], nil, ""))
+}
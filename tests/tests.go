@@ -63,5 +63,7 @@ func init() {
 
 func refreshDb() {
 	GormInitWithSqlite("./test.db")
-	RunMigrations(new(CustomTree))
+	RunMigrations(new(CustomTree), new(UniqueTree), new(ScopedTree), new(OrderedTree),
+		new(UUIDTree), new(BinaryTree), new(StringTree), new(Uint64Tree), new(PtrParentTree), new(NullParentTree),
+		new(HookedTree), new(SoftTree), new(Product), new(Category), new(SoftCategory))
 }
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func createUniqueTree(t *testing.T, manager mptt.TreeManager) map[string]*UniqueTree {
+	assert.Nil(t, cleanTable(new(UniqueTree)))
+	nodes := make(map[string]*UniqueTree)
+	for _, name := range []string{"root", "a", "b"} {
+		node := &UniqueTree{Name: name}
+		if name != "root" {
+			node.ParentID = nodes["root"].ID
+		}
+		assert.Nil(t, manager.CreateNode(node))
+		nodes[name] = node
+	}
+	return nodes
+}
+
+func getUniqueTree(t *testing.T, manager mptt.TreeManager) map[string]UniqueTree {
+	var rows []UniqueTree
+	assert.Nil(t, manager.GormDB().Model(new(UniqueTree)).Find(&rows).Error)
+	ret := make(map[string]UniqueTree)
+	for _, row := range rows {
+		ret[row.Name] = row
+	}
+	return ret
+}
+
+func TestCreateNodeRollback(t *testing.T) {
+	manager, err := mptt.NewTreeManager(globalDb, new(UniqueTree))
+	assert.Nil(t, err)
+	nodes := createUniqueTree(t, manager)
+	before := getUniqueTree(t, manager)
+
+	// the space is created before the insert fails on the unique index
+	err = manager.InsertNode(&UniqueTree{Name: "b"}, nodes["a"], mptt.Left)
+	assert.NotNil(t, err)
+	assert.Equal(t, before, getUniqueTree(t, manager))
+
+	err = manager.CreateNode(&UniqueTree{
+		ModelBase: mptt.ModelBase{ParentID: nodes["root"].ID},
+		Name:      "a",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, before, getUniqueTree(t, manager))
+}
+
+func TestJoinOuterTransaction(t *testing.T) {
+	manager, err := mptt.NewTreeManager(globalDb, new(UniqueTree))
+	assert.Nil(t, err)
+	nodes := createUniqueTree(t, manager)
+	before := getUniqueTree(t, manager)
+
+	err = globalDb.Transaction(func(tx *gorm.DB) error {
+		txManager, err := mptt.NewTreeManager(tx, new(UniqueTree))
+		if err != nil {
+			return err
+		}
+		if _, err = txManager.MoveNode(nodes["b"], nodes["a"], mptt.Left); err != nil {
+			return err
+		}
+		// a failed operation only rolls back its own statements
+		err = txManager.InsertNode(&UniqueTree{Name: "a"}, nodes["a"], mptt.FirstChild)
+		assert.NotNil(t, err)
+		return txManager.RefreshNode(nodes["a"])
+	})
+	assert.Nil(t, err)
+	after := getUniqueTree(t, manager)
+	assert.EqualValues(t, 2, after["b"].Lft)
+	assert.EqualValues(t, 4, after["a"].Lft)
+	assert.EqualValues(t, before["root"], after["root"])
+
+	err = globalDb.Transaction(func(tx *gorm.DB) error {
+		txManager, err := mptt.NewTreeManager(tx, new(UniqueTree))
+		if err != nil {
+			return err
+		}
+		if err = txManager.DeleteNodeByID(nodes["a"].ID); err != nil {
+			return err
+		}
+		return gorm.ErrInvalidTransaction
+	})
+	assert.Equal(t, gorm.ErrInvalidTransaction, err)
+	assert.Equal(t, after, getUniqueTree(t, manager))
+}
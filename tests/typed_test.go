@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"context"
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTypedTreeManager(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	ctx := context.Background()
+	manager, err := mptt.NewTypedTreeManager[CustomTree](globalDb)
+	assert.Nil(t, err)
+	for _, node := range rawNodes {
+		err = dfs(node, func(n *Node) (int, error) {
+			item := &CustomTree{ModelBase: mptt.ModelBase{ParentID: n.ParentID}, Name: n.Name}
+			err := manager.Create(ctx, item)
+			return item.ID, err
+		})
+		assert.Nil(t, err)
+	}
+
+	nodeByName, err := getAllNodes(manager.Manager())
+	assert.Nil(t, err)
+	devCenter := nodeByName["dev center"]
+	descendants, err := manager.Descendants(ctx, devCenter, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(descendants))
+	assert.Equal(t, "dev center", descendants[0].Name)
+	assert.Equal(t, "dev team 4", descendants[6].Name)
+
+	children, err := manager.Children(ctx, devCenter)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(children))
+	assert.True(t, manager.IsDescendantOf(children[0], devCenter, false))
+	assert.True(t, manager.IsAncestorOf(devCenter, children[1], false))
+
+	next, err := manager.NextSibling(ctx, children[0])
+	assert.Nil(t, err)
+	assert.Equal(t, children[1].ID, next.ID)
+
+	// a nil target makes the node a new root
+	assert.Nil(t, manager.Move(ctx, devCenter, nil, mptt.LastChild))
+	assert.Equal(t, 3, devCenter.TreeID)
+	assert.Nil(t, manager.Refresh(ctx, children[0]))
+	root, err := manager.Root(ctx, children[0])
+	assert.Nil(t, err)
+	assert.Equal(t, devCenter.ID, root.ID)
+
+	got, err := manager.Get(ctx, devCenter.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, devCenter.Rght, got.Rght)
+	ancestors, err := manager.Ancestors(ctx, children[0], true, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(ancestors))
+
+	assert.Nil(t, manager.Delete(ctx, devCenter))
+	_, err = manager.Get(ctx, devCenter.ID)
+	assert.NotNil(t, err)
+}
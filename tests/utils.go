@@ -2,6 +2,7 @@ package tests
 
 import (
 	mptt "github.com/boycs007/gorm-mptt"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -73,3 +74,8 @@ func getAllNodes(manager mptt.TreeManager) (map[string]*CustomTree, error) {
 	}
 	return retMap, err
 }
+
+// cleanTable remove all rows of the model's table
+func cleanTable(model interface{}) error {
+	return globalDb.Session(&gorm.Session{NewDB: true, AllowGlobalUpdate: true}).Unscoped().Delete(model).Error
+}
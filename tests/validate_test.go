@@ -0,0 +1,107 @@
+package tests
+
+import (
+	mptt "github.com/boycs007/gorm-mptt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, cleanTable(new(CustomTree)))
+	defer cleanTable(new(CustomTree))
+	manager, err := mptt.NewTreeManager(globalDb, new(CustomTree))
+	assert.Nil(t, err)
+	for _, node := range rawNodes {
+		err = bfs(node, func(n *Node) (int, error) {
+			return rawCreate(manager, n)
+		})
+		assert.Nil(t, err)
+	}
+
+	// rawCreate does not maintain the mptt columns
+	report, err := manager.Validate()
+	assert.Nil(t, err)
+	assert.False(t, report.Valid())
+
+	_, err = manager.FastRebuild()
+	assert.Nil(t, err)
+	report, err = manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid())
+	assert.EqualValues(t, 30, report.NodeCount)
+
+	nodeMap, err := getAllNodes(manager)
+	assert.Nil(t, err)
+	var (
+		root  = nodeMap["dev department"]
+		team1 = nodeMap["dev team 1"]
+		team2 = nodeMap["dev team 2"]
+		// Update also writes the value back to the model
+		team2Parent = team2.ParentID
+	)
+	corrupt := func(node *CustomTree, column string, value interface{}) *mptt.IntegrityReport {
+		assert.Nil(t, globalDb.Model(node).Update(column, value).Error)
+		report, err := manager.Validate()
+		assert.Nil(t, err)
+		assert.EqualValues(t, []int{1}, report.TreeIDs)
+		_, err = manager.FastRebuild()
+		assert.Nil(t, err)
+		return report
+	}
+
+	report = corrupt(team2, "lvl", 5)
+	assert.EqualValues(t, []interface{}{team2.ID}, report.Violations[mptt.ViolationLevel])
+	assert.Len(t, report.Violations, 1)
+
+	report = corrupt(team1, "rght", 3)
+	assert.Contains(t, report.Violations[mptt.ViolationInvalidInterval], team1.ID)
+	assert.Contains(t, report.Violations[mptt.ViolationDuplicateNumber], team1.ID)
+	assert.Contains(t, report.Violations[mptt.ViolationNumberingGap], team2.ID)
+
+	report = corrupt(team1, "rght", 7)
+	assert.ElementsMatch(t, []interface{}{team1.ID, team2.ID}, report.Violations[mptt.ViolationOverlap])
+	assert.ElementsMatch(t, []interface{}{team1.ID, team2.ID}, report.Violations[mptt.ViolationDuplicateNumber])
+
+	assert.Nil(t, globalDb.Model(team2).Update("parent_id", nodeMap["dev center"].ID).Error)
+	report, err = manager.Validate()
+	assert.Nil(t, err)
+	assert.EqualValues(t, []interface{}{team2.ID}, report.Violations[mptt.ViolationParent])
+	assert.Len(t, report.Violations, 1)
+	assert.Nil(t, globalDb.Model(team2).Update("parent_id", team2Parent).Error)
+
+	assert.Nil(t, globalDb.Model(team2).Update("parent_id", 99999).Error)
+	report, err = manager.Validate()
+	assert.Nil(t, err)
+	assert.EqualValues(t, []interface{}{team2.ID}, report.Violations[mptt.ViolationOrphan])
+	assert.Len(t, report.Violations, 1)
+
+	assert.Nil(t, globalDb.Model(team2).Update("parent_id", 0).Error)
+	report, err = manager.Validate()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []interface{}{root.ID, team2.ID}, report.Violations[mptt.ViolationMultipleRoots])
+	assert.EqualValues(t, []interface{}{team2.ID}, report.Violations[mptt.ViolationParent])
+	assert.Nil(t, globalDb.Model(team2).Update("parent_id", team2Parent).Error)
+
+	var root2 *CustomTree
+	for _, node := range nodeMap {
+		if node.TreeID == 2 && node.ParentID == 0 {
+			root2 = node
+		}
+	}
+	err = globalDb.Model(new(CustomTree)).Where("tree_id = ?", 2).Update("tree_id", 5).Error
+	assert.Nil(t, err)
+	report, err = manager.Validate()
+	assert.Nil(t, err)
+	assert.EqualValues(t, []interface{}{root2.ID}, report.Violations[mptt.ViolationTreeIDGap])
+	assert.EqualValues(t, []int{5}, report.TreeIDs)
+	// tree_id contiguity is only checked for the whole table
+	report, err = manager.Validate(5)
+	assert.Nil(t, err)
+	assert.True(t, report.Valid())
+
+	_, err = manager.FastRebuild()
+	assert.Nil(t, err)
+	report, err = manager.Validate()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid())
+}
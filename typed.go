@@ -0,0 +1,318 @@
+package mptt
+
+import (
+	"context"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// TypedTreeManager type-safe TreeManager for model T, nodes are passed as *T
+type TypedTreeManager[T any] interface {
+	// Manager the untyped TreeManager sharing the same configuration
+	Manager() TreeManager
+
+	Get(ctx context.Context, id interface{}) (*T, error)
+	Refresh(ctx context.Context, node *T) error
+	Create(ctx context.Context, node *T) error
+	// Save move node when its parent changed, then save the other columns
+	Save(ctx context.Context, node *T) error
+	Insert(ctx context.Context, node, target *T, position PositionEnum) error
+	// Move node to the position relative to target, a nil target makes node a new root
+	Move(ctx context.Context, node, target *T, position PositionEnum) error
+	Delete(ctx context.Context, node *T) error
+	// DeleteKeepChildren delete only node, its children take its place
+	DeleteKeepChildren(ctx context.Context, node *T) error
+	// Restore re-insert the soft-deleted subtree under its old parent when target is nil
+	Restore(ctx context.Context, node, target *T, position PositionEnum) error
+	ReorderChildren(ctx context.Context, parent *T) error
+	// Copy copy source and its descendants to the position relative to target, a nil target makes a new tree
+	Copy(ctx context.Context, source, target *T, position PositionEnum, opts ...CopyOption) (*T, error)
+	// Import insert roots and their nested children in batches
+	Import(ctx context.Context, roots []*T, opts ...ImportOption) error
+	// ExportJSON write root and its descendants as nested json, all the trees when root is nil
+	ExportJSON(ctx context.Context, w io.Writer, root *T, opts ...JSONOption) error
+	// ImportJSON insert the json written by ExportJSON at the position relative to target, a nil target makes new trees
+	ImportJSON(ctx context.Context, r io.Reader, target *T, position PositionEnum, opts ...JSONOption) error
+	// ExportCSV write root and its descendants as csv rows, all the trees when root is nil
+	ExportCSV(ctx context.Context, w io.Writer, root *T, opts ...CSVOption) error
+	// ImportCSV insert the adjacency or path rows of the csv
+	ImportCSV(ctx context.Context, r io.Reader, opts ...CSVOption) (*CSVImportReport, error)
+	// RenderDOT write root and its descendants as a graphviz digraph, all the trees when root is nil
+	RenderDOT(ctx context.Context, w io.Writer, root *T, opts ...RenderOption) error
+	// RenderMermaid write root and its descendants as a mermaid flowchart, all the trees when root is nil
+	RenderMermaid(ctx context.Context, w io.Writer, root *T, opts ...RenderOption) error
+	// PrintTree print node and its descendants with ├──/└── connectors, all the trees when node is nil
+	PrintTree(ctx context.Context, w io.Writer, node *T, label func(node *T) string, opts ...RenderOption) error
+	Rebuild(ctx context.Context) error
+
+	Ancestors(ctx context.Context, node *T, ascending, includeSelf bool) ([]*T, error)
+	Descendants(ctx context.Context, node *T, includeSelf bool) ([]*T, error)
+	Family(ctx context.Context, node *T) ([]*T, error)
+	Children(ctx context.Context, node *T) ([]*T, error)
+	LeafNodes(ctx context.Context, node *T) ([]*T, error)
+	Siblings(ctx context.Context, node *T, includeSelf bool) ([]*T, error)
+	NextSibling(ctx context.Context, node *T, conds ...interface{}) (*T, error)
+	PreviousSibling(ctx context.Context, node *T, conds ...interface{}) (*T, error)
+	Root(ctx context.Context, node *T) (*T, error)
+	// Tree the node with its nested descendants
+	Tree(ctx context.Context, node *T, opts ...LoadOption) (*T, error)
+	// Forest all the root nodes with their nested descendants
+	Forest(ctx context.Context, opts ...LoadOption) ([]*T, error)
+	// AnnotateCumulativeCount the nodes with outField set to the number of related rows of each subtree
+	AnnotateCumulativeCount(ctx context.Context, related interface{}, fkColumn, outField string, opts ...AggregateOption) ([]*T, error)
+	// AggregateSubtree the nodes with outField set to expr over the related rows of each subtree
+	AggregateSubtree(ctx context.Context, related interface{}, fkColumn, expr, outField string, opts ...AggregateOption) ([]*T, error)
+
+	IsDescendantOf(node, other *T, includeSelf bool) bool
+	IsAncestorOf(node, other *T, includeSelf bool) bool
+}
+
+type typedTree[T any] struct {
+	*tree
+}
+
+// NewTypedTreeManager create mptt tree manager for model T
+func NewTypedTreeManager[T any](db *gorm.DB, opts ...Option) (TypedTreeManager[T], error) {
+	manager, err := NewTreeManager(db, new(T), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &typedTree[T]{tree: manager.(*tree)}, nil
+}
+
+func (m *typedTree[T]) Manager() TreeManager {
+	return m.tree
+}
+
+// ctx bind the tree to ctx
+func (m *typedTree[T]) ctx(ctx context.Context) *tree {
+	return m.tree.WithContext(ctx).(*tree)
+}
+
+func (m *typedTree[T]) Get(ctx context.Context, id interface{}) (*T, error) {
+	node, err := m.ctx(ctx).getNodeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return node.(*T), nil
+}
+
+func (m *typedTree[T]) Refresh(ctx context.Context, node *T) error {
+	return m.ctx(ctx).RefreshNode(node)
+}
+
+func (m *typedTree[T]) Create(ctx context.Context, node *T) error {
+	return m.ctx(ctx).CreateNode(node)
+}
+
+func (m *typedTree[T]) Save(ctx context.Context, node *T) error {
+	return m.ctx(ctx).SaveNode(node)
+}
+
+func (m *typedTree[T]) Insert(ctx context.Context, node, target *T, position PositionEnum) error {
+	return m.ctx(ctx).InsertNode(node, target, position)
+}
+
+func (m *typedTree[T]) Move(ctx context.Context, node, target *T, position PositionEnum) error {
+	var targetPtr interface{}
+	if target != nil {
+		targetPtr = target
+	}
+	_, err := m.ctx(ctx).MoveNode(node, targetPtr, position)
+	return err
+}
+
+func (m *typedTree[T]) Delete(ctx context.Context, node *T) error {
+	return m.ctx(ctx).DeleteNode(node)
+}
+
+func (m *typedTree[T]) DeleteKeepChildren(ctx context.Context, node *T) error {
+	return m.ctx(ctx).DeleteNodeKeepChildren(node)
+}
+
+func (m *typedTree[T]) Restore(ctx context.Context, node, target *T, position PositionEnum) error {
+	var targetPtr interface{}
+	if target != nil {
+		targetPtr = target
+	}
+	return m.ctx(ctx).RestoreNode(node, targetPtr, position)
+}
+
+func (m *typedTree[T]) Copy(ctx context.Context, source, target *T, position PositionEnum, opts ...CopyOption) (*T, error) {
+	var targetPtr interface{}
+	if target != nil {
+		targetPtr = target
+	}
+	root, err := m.ctx(ctx).CopySubtree(source, targetPtr, position, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return root.(*T), nil
+}
+
+func (m *typedTree[T]) Import(ctx context.Context, roots []*T, opts ...ImportOption) error {
+	return m.ctx(ctx).ImportTree(roots, opts...)
+}
+
+func (m *typedTree[T]) ExportJSON(ctx context.Context, w io.Writer, root *T, opts ...JSONOption) error {
+	var rootPtr interface{}
+	if root != nil {
+		rootPtr = root
+	}
+	return m.ctx(ctx).ExportJSON(w, rootPtr, opts...)
+}
+
+func (m *typedTree[T]) ImportJSON(ctx context.Context, r io.Reader, target *T, position PositionEnum, opts ...JSONOption) error {
+	var targetPtr interface{}
+	if target != nil {
+		targetPtr = target
+	}
+	return m.ctx(ctx).ImportJSON(r, targetPtr, position, opts...)
+}
+
+func (m *typedTree[T]) ExportCSV(ctx context.Context, w io.Writer, root *T, opts ...CSVOption) error {
+	var rootPtr interface{}
+	if root != nil {
+		rootPtr = root
+	}
+	return m.ctx(ctx).ExportCSV(w, rootPtr, opts...)
+}
+
+func (m *typedTree[T]) ImportCSV(ctx context.Context, r io.Reader, opts ...CSVOption) (*CSVImportReport, error) {
+	return m.ctx(ctx).ImportCSV(r, opts...)
+}
+
+func (m *typedTree[T]) RenderDOT(ctx context.Context, w io.Writer, root *T, opts ...RenderOption) error {
+	var rootPtr interface{}
+	if root != nil {
+		rootPtr = root
+	}
+	return m.ctx(ctx).RenderDOT(w, rootPtr, opts...)
+}
+
+func (m *typedTree[T]) RenderMermaid(ctx context.Context, w io.Writer, root *T, opts ...RenderOption) error {
+	var rootPtr interface{}
+	if root != nil {
+		rootPtr = root
+	}
+	return m.ctx(ctx).RenderMermaid(w, rootPtr, opts...)
+}
+
+func (m *typedTree[T]) PrintTree(ctx context.Context, w io.Writer, node *T, label func(node *T) string, opts ...RenderOption) error {
+	var (
+		nodePtr   interface{}
+		labelFunc func(node interface{}) string
+	)
+	if node != nil {
+		nodePtr = node
+	}
+	if label != nil {
+		labelFunc = func(node interface{}) string {
+			return label(node.(*T))
+		}
+	}
+	return m.ctx(ctx).PrintTree(w, nodePtr, labelFunc, opts...)
+}
+
+func (m *typedTree[T]) ReorderChildren(ctx context.Context, parent *T) error {
+	return m.ctx(ctx).ReorderChildren(parent)
+}
+
+func (m *typedTree[T]) Rebuild(ctx context.Context) error {
+	return m.ctx(ctx).Rebuild()
+}
+
+func (m *typedTree[T]) Ancestors(ctx context.Context, node *T, ascending, includeSelf bool) ([]*T, error) {
+	var nodes []*T
+	err := m.ctx(ctx).Node(node).GetAncestors(&nodes, ascending, includeSelf)
+	return nodes, err
+}
+
+func (m *typedTree[T]) Descendants(ctx context.Context, node *T, includeSelf bool) ([]*T, error) {
+	var nodes []*T
+	err := m.ctx(ctx).Node(node).GetDescendants(&nodes, includeSelf)
+	return nodes, err
+}
+
+func (m *typedTree[T]) Family(ctx context.Context, node *T) ([]*T, error) {
+	var nodes []*T
+	err := m.ctx(ctx).Node(node).GetFamily(&nodes)
+	return nodes, err
+}
+
+func (m *typedTree[T]) Children(ctx context.Context, node *T) ([]*T, error) {
+	var nodes []*T
+	err := m.ctx(ctx).Node(node).GetChildren(&nodes)
+	return nodes, err
+}
+
+func (m *typedTree[T]) LeafNodes(ctx context.Context, node *T) ([]*T, error) {
+	var nodes []*T
+	err := m.ctx(ctx).Node(node).GetLeafNodes(&nodes)
+	return nodes, err
+}
+
+func (m *typedTree[T]) Siblings(ctx context.Context, node *T, includeSelf bool) ([]*T, error) {
+	var nodes []*T
+	err := m.ctx(ctx).Node(node).GetSiblings(&nodes, includeSelf)
+	return nodes, err
+}
+
+func (m *typedTree[T]) NextSibling(ctx context.Context, node *T, conds ...interface{}) (*T, error) {
+	var sibling T
+	if err := m.ctx(ctx).Node(node).GetNextSibling(&sibling, conds...); err != nil {
+		return nil, err
+	}
+	return &sibling, nil
+}
+
+func (m *typedTree[T]) PreviousSibling(ctx context.Context, node *T, conds ...interface{}) (*T, error) {
+	var sibling T
+	if err := m.ctx(ctx).Node(node).GetPreviousSibling(&sibling, conds...); err != nil {
+		return nil, err
+	}
+	return &sibling, nil
+}
+
+func (m *typedTree[T]) Root(ctx context.Context, node *T) (*T, error) {
+	var root T
+	if err := m.ctx(ctx).Node(node).GetRoot(&root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+func (m *typedTree[T]) Tree(ctx context.Context, node *T, opts ...LoadOption) (*T, error) {
+	var root T
+	if err := m.ctx(ctx).Node(node).GetTree(&root, opts...); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+func (m *typedTree[T]) Forest(ctx context.Context, opts ...LoadOption) ([]*T, error) {
+	var roots []*T
+	err := m.ctx(ctx).LoadForest(&roots, opts...)
+	return roots, err
+}
+
+func (m *typedTree[T]) AnnotateCumulativeCount(ctx context.Context, related interface{}, fkColumn, outField string, opts ...AggregateOption) ([]*T, error) {
+	var nodes []*T
+	err := m.ctx(ctx).AnnotateCumulativeCount(&nodes, related, fkColumn, outField, opts...)
+	return nodes, err
+}
+
+func (m *typedTree[T]) AggregateSubtree(ctx context.Context, related interface{}, fkColumn, expr, outField string, opts ...AggregateOption) ([]*T, error) {
+	var nodes []*T
+	err := m.ctx(ctx).AggregateSubtree(&nodes, related, fkColumn, expr, outField, opts...)
+	return nodes, err
+}
+
+func (m *typedTree[T]) IsDescendantOf(node, other *T, includeSelf bool) bool {
+	return m.Node(node).IsDescendantOf(other, includeSelf)
+}
+
+func (m *typedTree[T]) IsAncestorOf(node, other *T, includeSelf bool) bool {
+	return m.Node(node).IsAncestorOf(other, includeSelf)
+}
@@ -0,0 +1,221 @@
+package mptt
+
+import (
+	"sort"
+	"strings"
+)
+
+// ViolationKind kind of the mptt integrity violations
+type ViolationKind string
+
+const (
+	// ViolationInvalidInterval rght <= lft
+	ViolationInvalidInterval ViolationKind = "invalid_interval"
+	// ViolationOverlap the interval crosses the interval of another node
+	ViolationOverlap ViolationKind = "overlap"
+	// ViolationDuplicateNumber lft or rght value used more than once in a tree
+	ViolationDuplicateNumber ViolationKind = "duplicate_number"
+	// ViolationNumberingGap lft and rght values of a tree are not the sequence 1..2n,
+	// reported on the nodes right after the missing numbers
+	ViolationNumberingGap ViolationKind = "numbering_gap"
+	// ViolationLevel lvl is not the depth of the enclosing intervals + 1
+	ViolationLevel ViolationKind = "level"
+	// ViolationParent parent_id is not the node with the tightest enclosing interval
+	ViolationParent ViolationKind = "parent"
+	// ViolationOrphan the parent row does not exist
+	ViolationOrphan ViolationKind = "orphan"
+	// ViolationMultipleRoots more than one root share a tree_id
+	ViolationMultipleRoots ViolationKind = "multiple_roots"
+	// ViolationTreeIDGap tree_id values of a scope are not the sequence 1..k,
+	// reported on the roots whose tree_id is out of sequence
+	ViolationTreeIDGap ViolationKind = "tree_id_gap"
+)
+
+// IntegrityReport result of Validate
+type IntegrityReport struct {
+	// NodeCount the number of checked nodes
+	NodeCount int
+	// Violations the offending node ids of each violation kind
+	Violations map[ViolationKind][]interface{}
+	// TreeIDs the trees which have violations, e.g. to PartialRebuild them
+	TreeIDs []int
+	// seen 各类问题已记录的id
+	seen map[ViolationKind]map[interface{}]struct{}
+}
+
+// Valid no violation is found
+func (r *IntegrityReport) Valid() bool {
+	return len(r.Violations) == 0
+}
+
+func (r *IntegrityReport) add(kind ViolationKind, treeID int, id interface{}) {
+	if r.seen == nil {
+		r.seen = make(map[ViolationKind]map[interface{}]struct{})
+	}
+	if r.seen[kind] == nil {
+		r.seen[kind] = make(map[interface{}]struct{})
+	}
+	if _, ok := r.seen[kind][idKey(id)]; ok {
+		return
+	}
+	r.seen[kind][idKey(id)] = struct{}{}
+	r.Violations[kind] = append(r.Violations[kind], id)
+	index := sort.SearchInts(r.TreeIDs, treeID)
+	if index == len(r.TreeIDs) || r.TreeIDs[index] != treeID {
+		r.TreeIDs = append(r.TreeIDs, 0)
+		copy(r.TreeIDs[index+1:], r.TreeIDs[index:])
+		r.TreeIDs[index] = treeID
+	}
+}
+
+// Validate 检查树的MPTT信息是否正确，不传treeID时检查全部数据
+func (t *tree) Validate(treeID ...int) (*IntegrityReport, error) {
+	columns := []string{t.colID(), t.colParent(), t.colTree(), t.colLeft(), t.colRight(), t.colLevel()}
+	orders := make([]string, 0, len(t.scopes)+2)
+	for _, field := range t.scopes {
+		columns = append(columns, t.Statement.Quote(field.DBName))
+		orders = append(orders, t.Statement.Quote(field.DBName)+" ASC")
+	}
+	orders = append(orders, t.colTree()+" ASC", t.colLeft()+" ASC")
+	query := t.slotRows(reflectNew(t.node)).Select(columns).Order(strings.Join(orders, ", "))
+	if len(treeID) > 0 {
+		query = query.Where(t.colTree()+" IN ?", treeID)
+	}
+	nodes, err := t.findNodes(query)
+	if err != nil {
+		return nil, err
+	}
+	report := &IntegrityReport{
+		NodeCount:  len(nodes),
+		Violations: make(map[ViolationKind][]interface{}),
+	}
+
+	exists, err := t.existingIDs(nodes)
+	if err != nil {
+		return nil, err
+	}
+	orphans := make(map[interface{}]struct{})
+	for _, node := range nodes {
+		if !t.isRootNode(node) {
+			if _, ok := exists[idKey(t.getParentID(node))]; !ok {
+				orphans[idKey(t.getNodeID(node))] = struct{}{}
+				report.add(ViolationOrphan, t.getTreeID(node), t.getNodeID(node))
+			}
+		}
+	}
+
+	var roots []interface{}
+	for start := 0; start < len(nodes); {
+		end := start + 1
+		for end < len(nodes) && t.sameScope(nodes[start], nodes[end]) &&
+			t.getTreeID(nodes[start]) == t.getTreeID(nodes[end]) {
+			end++
+		}
+		roots = append(roots, t.validateTree(report, nodes[start:end], orphans)...)
+		start = end
+	}
+
+	if len(treeID) == 0 {
+		// 每个scope中的tree_id应从1开始连续
+		expectTreeId := 1
+		for i, root := range roots {
+			if i > 0 && !t.sameScope(roots[i-1], root) {
+				expectTreeId = 1
+			}
+			if i > 0 && t.sameScope(roots[i-1], root) && t.getTreeID(roots[i-1]) == t.getTreeID(root) {
+				continue
+			}
+			if t.getTreeID(root) != expectTreeId {
+				report.add(ViolationTreeIDGap, t.getTreeID(root), t.getNodeID(root))
+			}
+			expectTreeId++
+		}
+	}
+	return report, nil
+}
+
+// validateTree 检查按lft排序的同一棵树的节点，返回树的根节点
+func (t *tree) validateTree(report *IntegrityReport, nodes []interface{}, orphans map[interface{}]struct{}) []interface{} {
+	var (
+		treeID  = t.getTreeID(nodes[0])
+		roots   []interface{}
+		holders = make(map[int][]interface{}, len(nodes)*2)
+		stack   []interface{}
+	)
+	for _, node := range nodes {
+		var (
+			id    = t.getNodeID(node)
+			left  = t.getLeft(node)
+			right = t.getRight(node)
+		)
+		holders[left] = append(holders[left], id)
+		holders[right] = append(holders[right], id)
+		if t.isRootNode(node) {
+			roots = append(roots, node)
+		}
+		if right <= left {
+			report.add(ViolationInvalidInterval, treeID, id)
+			continue
+		}
+
+		for len(stack) > 0 && t.getRight(stack[len(stack)-1]) < left {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 && t.getRight(stack[len(stack)-1]) <= right {
+			// 区间交叉，无法确定其所在的层级
+			report.add(ViolationOverlap, treeID, t.getNodeID(stack[len(stack)-1]))
+			report.add(ViolationOverlap, treeID, id)
+			continue
+		}
+		if t.getLevel(node) != len(stack)+1 {
+			report.add(ViolationLevel, treeID, id)
+		}
+		if _, ok := orphans[idKey(id)]; !ok {
+			if len(stack) == 0 {
+				if !t.isRootNode(node) {
+					report.add(ViolationParent, treeID, id)
+				}
+			} else if !t.equalIDValue(t.getParentID(node), t.getNodeID(stack[len(stack)-1])) {
+				report.add(ViolationParent, treeID, id)
+			}
+		}
+		stack = append(stack, node)
+	}
+
+	if len(roots) > 1 {
+		for _, root := range roots {
+			report.add(ViolationMultipleRoots, treeID, t.getNodeID(root))
+		}
+	}
+
+	// lft、rght应恰好为1..2n
+	maxNumber := len(nodes) * 2
+	for number, ids := range holders {
+		if number < 1 {
+			for _, id := range ids {
+				report.add(ViolationNumberingGap, treeID, id)
+			}
+		}
+		if number > maxNumber {
+			maxNumber = number
+		}
+	}
+	gap := false
+	for number := 1; number <= maxNumber; number++ {
+		ids, ok := holders[number]
+		if !ok {
+			gap = true
+			continue
+		}
+		for _, id := range ids {
+			if len(ids) > 1 {
+				report.add(ViolationDuplicateNumber, treeID, id)
+			}
+			if gap {
+				report.add(ViolationNumberingGap, treeID, id)
+			}
+		}
+		gap = false
+	}
+	return roots
+}